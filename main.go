@@ -1,17 +1,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"gopkg.in/yaml.v2"
 
+	"github.com/exaring/matroschka-prober/pkg/alert"
+	"github.com/exaring/matroschka-prober/pkg/capture"
 	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/correlation"
+	"github.com/exaring/matroschka-prober/pkg/diagnostics"
+	"github.com/exaring/matroschka-prober/pkg/events"
 	"github.com/exaring/matroschka-prober/pkg/frontend"
-	"github.com/exaring/matroschka-prober/pkg/prober"
-	"github.com/prometheus/client_golang/prometheus"
+	"github.com/exaring/matroschka-prober/pkg/frontend/otlp"
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/influx"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/exaring/matroschka-prober/pkg/recorder"
+	"github.com/exaring/matroschka-prober/pkg/segment"
+	"github.com/exaring/matroschka-prober/pkg/sink"
+	"github.com/exaring/matroschka-prober/pkg/tomography"
 	log "github.com/sirupsen/logrus"
 
 	_ "net/http/pprof"
@@ -20,6 +37,11 @@ import (
 var (
 	cfgFilepath = flag.String("config.file", "matroschka.yml", "Config file")
 	logLevel    = flag.String("log.level", "debug", "Log Level")
+	cfgCheck    = flag.Bool("config.check", false, "Validate the config file and exit")
+)
+
+const (
+	shutdownTimeout = 5 * time.Second
 )
 
 func main() {
@@ -33,79 +55,392 @@ func main() {
 	log.SetLevel(level)
 
 	cfg, err := loadConfig(*cfgFilepath)
+	if *cfgCheck {
+		os.Exit(checkConfig(*cfgFilepath, err))
+	}
+
 	if err != nil {
 		log.Errorf("Unable to load config: %v", err)
 		os.Exit(1)
 	}
 
-	confSrc, err := cfg.GetConfiguredSrcAddr()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var diag *diagnostics.Diagnostics
+	if cfg.Diagnostics != nil {
+		diag = diagnostics.New(ctx, diagnostics.Config{
+			LossThresholdPercent: *cfg.Diagnostics.LossThresholdPercent,
+			Duration:             time.Duration(*cfg.Diagnostics.DurationMS) * time.Millisecond,
+			Cooldown:             time.Duration(*cfg.Diagnostics.CooldownMS) * time.Millisecond,
+		})
+	}
+
+	mgr := manager.New(ctx)
+	if diag != nil {
+		mgr.AddObserver(diag)
+		mgr.AddCollector(diag)
+	}
+
+	eventLog, err := events.New(eventsConfig(cfg))
 	if err != nil {
-		log.Errorf("Unable to get configured src addr: %v", err)
+		log.Errorf("Unable to create event log: %v", err)
 		os.Exit(1)
 	}
+	defer eventLog.Close()
+	mgr.AddObserver(eventLog)
 
-	probers := make([]*prober.Prober, 0)
-	for i := range cfg.Paths {
-		for j := range cfg.Classes {
-			log.Infof("Starting prober for path %q class %q", cfg.Paths[i].Name, cfg.Classes[j].Name)
-			p, err := prober.New(prober.Config{
-				BasePort:          *cfg.BasePort,
-				ConfiguredSrcAddr: confSrc,
-				SrcAddrs:          config.GenerateAddrs(*cfg.SrcRange),
-				Hops:              cfg.PathToProberHops(cfg.Paths[i]),
-				StaticLabels:      []prober.Label{},
-				TOS: prober.TOS{
-					Name:  cfg.Classes[j].Name,
-					Value: cfg.Classes[j].TOS,
-				},
-				PPS:                 *cfg.Paths[i].PPS,
-				PayloadSizeBytes:    *cfg.Paths[i].PayloadSizeBytes,
-				MeasurementLengthMS: *cfg.Paths[i].MeasurementLengthMS,
-				TimeoutMS:           *cfg.Paths[i].TimeoutMS,
-			})
+	tracker := health.New(healthConfig(cfg))
+	tracker.AddListener(eventLog)
+	mgr.AddObserver(tracker)
+	mgr.AddCollector(tracker)
+
+	correlator := correlation.New(mgr, tracker)
+	mgr.AddCollector(correlator)
+	mgr.AddCollector(tomography.New(mgr))
+
+	segments := segment.New(segmentConfig(cfg))
+	mgr.AddObserver(segments)
+	mgr.AddCollector(segments)
+
+	alerter := alert.New(alertConfig(cfg))
+	mgr.AddObserver(alerter)
+	go alerter.Run(ctx)
+
+	// Exporters keep running until all probers are stopped to push their last measurements
+	exportCtx, stopExport := context.WithCancel(context.Background())
+	defer stopExport()
+	var exporters sync.WaitGroup
+
+	if cfg.OTLP != nil {
+		exporter, err := otlp.New(ctx, otlpConfig(cfg))
+		if err != nil {
+			log.Errorf("Unable to create OTLP exporter: %v", err)
+			os.Exit(1)
+		}
+		mgr.AddObserver(exporter)
+		exporters.Add(1)
+		go func() {
+			defer exporters.Done()
+			exporter.Run(exportCtx)
+		}()
+	}
+
+	sinks, err := createSinks(cfg)
+	if err != nil {
+		log.Errorf("Unable to create sinks: %v", err)
+		os.Exit(1)
+	}
+
+	if len(sinks) > 0 {
+		for _, s := range sinks {
+			exporters.Add(1)
+			go func(s sink.Sink) {
+				defer exporters.Done()
+				s.Run(exportCtx)
+			}(s)
+		}
+		mgr.AddObserver(sink.NewDispatcher(sinks...))
+	}
+
+	var rec *recorder.Recorder
+	if cfg.ProbeRecorder != nil {
+		rec = recorder.New(recorder.Config{
+			Dir:      cfg.ProbeRecorder.Dir,
+			MaxSize:  int64(*cfg.ProbeRecorder.MaxSizeMB) << 20,
+			MaxFiles: int(*cfg.ProbeRecorder.MaxFiles),
+		}, mgr)
+		mgr.SetRecorder(rec)
+		defer rec.Close()
+	}
+
+	var capturer *capture.Capturer
+	if cfg.Capture != nil {
+		capturer = capture.New(capture.Config{
+			Dir:         cfg.Capture.Dir,
+			MaxDuration: time.Duration(*cfg.Capture.MaxDurationMS) * time.Millisecond,
+			MaxPackets:  *cfg.Capture.MaxPackets,
+		}, mgr)
+		mgr.SetCapturer(capturer)
+		defer capturer.Close()
+	}
+
+	err = mgr.Reload(cfg)
+	if err != nil {
+		log.Errorf("Unable to start probers: %v", err)
+		os.Exit(1)
+	}
+
+	apiToken, err := getAPIToken(cfg)
+	if err != nil {
+		log.Errorf("Unable to get API token: %v", err)
+		os.Exit(1)
+	}
 
-			if err != nil {
-				log.Errorf("Unable to get new prober: %v", err)
-				os.Exit(1)
-			}
-
-			err = p.Start()
-			if err != nil {
-				log.Errorf("Unable to start prober: %v", err)
-				os.Exit(1)
-			}
-			probers = append(probers, p)
+	reload := func() error {
+		err := reloadConfig(mgr, tracker, alerter, segments)
+		if err != nil {
+			eventLog.Add(events.Event{
+				Time:    time.Now().UTC(),
+				Type:    events.TypeReload,
+				Message: fmt.Sprintf("Reload failed: %v", err),
+			})
+			return err
 		}
+
+		eventLog.Add(events.Event{
+			Time:    time.Now().UTC(),
+			Type:    events.TypeReload,
+			Message: "Config reloaded",
+		})
+		return nil
 	}
 
-	fe := frontend.New(&frontend.Config{
+	go handleSIGHUP(reload)
+
+	feCfg := &frontend.Config{
 		Version:       cfg.Version,
 		MetricsPath:   *cfg.MetricsPath,
 		ListenAddress: *cfg.ListenAddress,
-	}, newRegistry(probers))
+		Reload:        reload,
+		APIToken:      apiToken,
+		OpenReload:    cfg.API.AllowsUnauthenticatedReload(),
+		Health:        tracker,
+		Events:        eventLog,
+		Suspects:      correlator,
+	}
+	if rec != nil {
+		feCfg.Recorder = rec
+	}
+
+	if capturer != nil {
+		feCfg.Capturer = capturer
+	}
+
+	fe := frontend.New(feCfg, mgr)
 	go fe.Start()
-	select {}
+
+	<-ctx.Done()
+	log.Infof("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = fe.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Errorf("Unable to shut down frontend: %v", err)
+	}
+
+	mgr.Stop()
+	if diag != nil {
+		diag.Close()
+	}
+	log.Infof("All probers stopped")
+
+	stopExport()
+	if !waitTimeout(shutdownCtx, &exporters) {
+		log.Errorf("Unable to shut down exporters: %v", shutdownCtx.Err())
+	}
+}
+
+// waitTimeout waits for wg and returns false if ctx is done first
+func waitTimeout(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func reloadConfig(mgr *manager.Manager, tracker *health.Tracker, alerter *alert.Alerter, segments *segment.Calculator) error {
+	cfg, err := loadConfig(*cfgFilepath)
+	if err != nil {
+		return fmt.Errorf("Unable to load config: %v", err)
+	}
+
+	// The other components follow the config once the probers do. Probers that failed to start
+	// are part of the config nevertheless.
+	err = mgr.Reload(cfg)
+	var serr *manager.StartError
+	if err != nil && !errors.As(err, &serr) {
+		return err
+	}
+
+	tracker.Update(healthConfig(cfg))
+	alerter.Update(alertConfig(cfg))
+	segments.Update(segmentConfig(cfg))
+	return err
+}
+
+func handleSIGHUP(reload func() error) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	for range ch {
+		log.Infof("Received SIGHUP. Reloading config")
+		err := reload()
+		if err != nil {
+			log.Errorf("Unable to reload config: %v", err)
+		}
+	}
+}
+
+func getAPIToken(cfg *config.Config) (string, error) {
+	if cfg.API == nil {
+		return "", nil
+	}
+
+	return cfg.API.GetToken()
+}
+
+func eventsConfig(cfg *config.Config) events.Config {
+	ret := events.Config{
+		Size:         int(*cfg.Events.Size),
+		RTTStepRatio: *cfg.Events.RTTStepPercent / 100,
+		RTTStepMin:   time.Duration(*cfg.Events.RTTStepMinMS * float64(time.Millisecond)),
+	}
+
+	if cfg.Events.File != nil {
+		ret.File = *cfg.Events.File
+	}
+
+	return ret
+}
+
+func healthConfig(cfg *config.Config) health.Config {
+	return health.Config{
+		DegradedLossPercent: *cfg.Health.DegradedLossPercent,
+		DownLossPercent:     *cfg.Health.DownLossPercent,
+		DegradedRTTP99MS:    cfg.Health.DegradedRTTP99MS,
+		Hysteresis:          *cfg.Health.HysteresisPercent / 100,
+		Consecutive:         *cfg.Health.ConsecutiveBuckets,
+	}
+}
+
+func alertConfig(cfg *config.Config) alert.Config {
+	if cfg.Alerts == nil {
+		return alert.Config{}
+	}
+
+	ret := alert.Config{
+		WebhookURL: cfg.Alerts.WebhookURL,
+		Timeout:    time.Duration(*cfg.Alerts.TimeoutMS) * time.Millisecond,
+		Rules:      make([]alert.Rule, 0, len(cfg.Alerts.Rules)),
+	}
+
+	for _, r := range cfg.Alerts.Rules {
+		rule := alert.Rule{
+			Name:        r.Name,
+			Paths:       r.Paths,
+			Classes:     r.Classes,
+			LossPercent: r.LossPercent,
+			RTTP99MS:    r.RTTP99MS,
+			JitterMS:    r.JitterMS,
+			Consecutive: *r.ConsecutiveBuckets,
+			For:         time.Duration(*r.ForMS) * time.Millisecond,
+			Hysteresis:  *r.HysteresisPercent / 100,
+		}
+		ret.Rules = append(ret.Rules, rule)
+	}
+
+	return ret
 }
 
-type registry struct {
-	probers []*prober.Prober
+func createSinks(cfg *config.Config) ([]sink.Sink, error) {
+	sinks := make([]sink.Sink, 0)
+	if cfg.InfluxDB != nil {
+		s, err := influx.New(influxConfig(cfg))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create InfluxDB sink: %v", err)
+		}
+		sinks = append(sinks, s)
+	}
+
+	if cfg.FileSink != nil {
+		s, err := sink.NewFile(sink.FileConfig{
+			Path:     cfg.FileSink.Path,
+			MaxSize:  int64(*cfg.FileSink.MaxSizeMB) << 20,
+			MaxFiles: int(*cfg.FileSink.MaxFiles),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create file sink: %v", err)
+		}
+		sinks = append(sinks, s)
+	}
+
+	return sinks, nil
+}
+
+func influxConfig(cfg *config.Config) influx.Config {
+	ret := influx.Config{
+		URL:           cfg.InfluxDB.URL,
+		UDPAddress:    cfg.InfluxDB.UDPAddress,
+		Measurement:   *cfg.InfluxDB.Measurement,
+		BatchSize:     int(*cfg.InfluxDB.BatchSize),
+		FlushInterval: time.Duration(*cfg.InfluxDB.FlushIntervalMS) * time.Millisecond,
+		Timeout:       time.Duration(*cfg.InfluxDB.TimeoutMS) * time.Millisecond,
+		Retries:       int(*cfg.InfluxDB.Retries),
+	}
+
+	if cfg.InfluxDB.Token != nil {
+		ret.Token = *cfg.InfluxDB.Token
+	}
+
+	return ret
 }
 
-func newRegistry(probers []*prober.Prober) *registry {
-	return &registry{
-		probers: probers,
+func otlpConfig(cfg *config.Config) otlp.Config {
+	return otlp.Config{
+		Endpoint: cfg.OTLP.Endpoint,
+		Protocol: *cfg.OTLP.Protocol,
+		Insecure: *cfg.OTLP.Insecure,
+		Headers:  cfg.OTLP.Headers,
+		Timeout:  time.Duration(*cfg.OTLP.TimeoutMS) * time.Millisecond,
+		Version:  cfg.Version,
 	}
 }
 
-func (r *registry) GetCollectors() []prometheus.Collector {
-	ret := make([]prometheus.Collector, len(r.probers))
-	for i := range r.probers {
-		ret[i] = r.probers[i]
+func segmentConfig(cfg *config.Config) segment.Config {
+	ret := segment.Config{
+		Segments: make([]segment.Segment, 0, len(cfg.Segments)),
+	}
+
+	for _, s := range cfg.Segments {
+		ret.Segments = append(ret.Segments, segment.Segment{
+			Name:     s.Name,
+			Path:     s.Path,
+			Subtract: s.Subtract,
+		})
 	}
 
 	return ret
 }
 
+// checkConfig reports the result of loading the config and returns the exit code
+func checkConfig(path string, err error) int {
+	if err == nil {
+		fmt.Printf("Config file %q is valid\n", path)
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "Config file %q is invalid:\n", path)
+	if verr, ok := err.(*config.ValidationError); ok {
+		for _, e := range verr.Errors {
+			fmt.Fprintf(os.Stderr, "  - %v\n", e)
+		}
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "  - %v\n", err)
+	return 1
+}
+
 func loadConfig(path string) (*config.Config, error) {
 	cfgFile, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -113,11 +448,20 @@ func loadConfig(path string) (*config.Config, error) {
 	}
 
 	cfg := &config.Config{}
-	err = yaml.Unmarshal(cfgFile, cfg)
+	err = yaml.UnmarshalStrict(cfgFile, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to unmarshal: %v", err)
 	}
 
+	if cfg.MetricsPathDeprecated != nil {
+		log.Warnf("Config key metrcis_path is deprecated. Please use metrics_path instead")
+	}
+
 	cfg.ApplyDefaults()
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }
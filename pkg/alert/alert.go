@@ -0,0 +1,281 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	statusFiring   = "firing"
+	statusResolved = "resolved"
+)
+
+// Config is the configuration of the alerter
+type Config struct {
+	WebhookURL string
+	Timeout    time.Duration
+	Rules      []Rule
+}
+
+// Rule is an alert rule. Thresholds that are nil are not checked.
+type Rule struct {
+	Name        string
+	Paths       []string // Patterns as understood by path.Match. All paths if empty.
+	Classes     []string // All classes if empty
+	LossPercent *float64
+	RTTP99MS    *float64
+	JitterMS    *float64
+	Consecutive uint64        // Failing (or passing) buckets required to fire (or resolve)
+	For         time.Duration // Time the rule has to fail before firing
+	Hysteresis  float64       // Ratio values must drop below the thresholds to resolve
+}
+
+// Alerter evaluates alert rules against finished measurements and notifies a webhook
+// about firing and resolved alerts
+type Alerter struct {
+	cfg    Config
+	states map[stateKey]*state
+	queue  chan *Notification
+	client *http.Client
+	l      sync.Mutex
+}
+
+type stateKey struct {
+	rule   string
+	prober string
+}
+
+type state struct {
+	firing       bool
+	failing      uint64
+	passing      uint64
+	pendingSince int64
+	firingSince  int64
+	fired        *Notification // Notification the alert fired with
+}
+
+type verdict int
+
+const (
+	verdictPassing verdict = iota
+	verdictFailing
+	verdictUndecided // Between the resolve and the fire threshold
+)
+
+// New creates a new alerter
+func New(cfg Config) *Alerter {
+	return &Alerter{
+		cfg:    cfg,
+		states: make(map[stateKey]*state),
+		queue:  make(chan *Notification, queueSize),
+		client: &http.Client{},
+	}
+}
+
+// Update replaces the configuration. The state of rules that still exist is kept.
+func (a *Alerter) Update(cfg Config) {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	rules := make(map[string]struct{}, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rules[r.Name] = struct{}{}
+	}
+
+	for k := range a.states {
+		if _, ok := rules[k.rule]; !ok {
+			delete(a.states, k)
+		}
+	}
+
+	a.cfg = cfg
+}
+
+// Observe evaluates all rules matching the prober against a finished measurement
+func (a *Alerter) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	if m.Sent == 0 {
+		return
+	}
+
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	end := ts + int64(p.Config().MeasurementLengthMS)*int64(time.Millisecond)
+	for i := range a.cfg.Rules {
+		r := &a.cfg.Rules[i]
+		if !r.matches(p.PathName(), p.TOS().Name) {
+			continue
+		}
+
+		k := stateKey{
+			rule:   r.Name,
+			prober: p.ID(),
+		}
+
+		s := a.states[k]
+		if s == nil {
+			s = &state{}
+			a.states[k] = s
+		}
+
+		status := s.update(r, r.check(m), ts, end)
+		if status == "" {
+			continue
+		}
+
+		n := newNotification(status, r, p, s, end, m)
+		s.fired = nil
+		if status == statusFiring {
+			s.fired = n
+		}
+
+		log.Infof("Alert %q for prober %q is %s", r.Name, p.ID(), status)
+		a.enqueue(n)
+	}
+}
+
+// Forget resolves the firing alerts of a removed prober and drops its state
+func (a *Alerter) Forget(id string) {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	for k, s := range a.states {
+		if k.prober != id {
+			continue
+		}
+
+		if s.fired != nil {
+			n := *s.fired
+			endsAt := time.Now().UTC()
+			n.Status = statusResolved
+			n.EndsAt = &endsAt
+			log.Infof("Alert %q for prober %q is resolved as the prober was removed", k.rule, id)
+			a.enqueue(&n)
+		}
+
+		delete(a.states, k)
+	}
+}
+
+// Run sends queued notifications to the webhook until ctx is cancelled
+func (a *Alerter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-a.queue:
+			a.send(ctx, n)
+		}
+	}
+}
+
+func (r *Rule) matches(pathName string, class string) bool {
+	if len(r.Classes) > 0 && !contains(r.Classes, class) {
+		return false
+	}
+
+	if len(r.Paths) == 0 {
+		return true
+	}
+
+	for _, pattern := range r.Paths {
+		if ok, _ := path.Match(pattern, pathName); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// check compares a measurement against the thresholds of a rule
+func (r *Rule) check(m *measurement.Measurement) verdict {
+	v := newValues(m)
+	failing := false
+	passing := true
+
+	for _, c := range []struct {
+		threshold *float64
+		value     float64
+	}{
+		{r.LossPercent, v.LossPercent},
+		{r.RTTP99MS, v.RTTP99MS},
+		{r.JitterMS, v.JitterMS},
+	} {
+		if c.threshold == nil {
+			continue
+		}
+
+		if c.value >= *c.threshold {
+			failing = true
+		}
+
+		if c.value >= *c.threshold*(1-r.Hysteresis) {
+			passing = false
+		}
+	}
+
+	switch {
+	case failing:
+		return verdictFailing
+	case passing:
+		return verdictPassing
+	default:
+		return verdictUndecided
+	}
+}
+
+// update advances the state of a rule by a bucket starting at ts and ending at end.
+// It returns the new status if the alert fired or resolved and an empty string otherwise.
+func (s *state) update(r *Rule, v verdict, ts int64, end int64) string {
+	switch v {
+	case verdictFailing:
+		s.passing = 0
+		if s.failing == 0 {
+			s.pendingSince = ts
+		}
+		s.failing++
+
+		if !s.firing && s.failing >= r.Consecutive && time.Duration(end-s.pendingSince) >= r.For {
+			s.firing = true
+			s.firingSince = s.pendingSince
+			return statusFiring
+		}
+	case verdictPassing:
+		s.failing = 0
+		if !s.firing {
+			return ""
+		}
+
+		s.passing++
+		if s.passing >= r.Consecutive {
+			s.firing = false
+			s.passing = 0
+			return statusResolved
+		}
+	case verdictUndecided:
+		if s.firing {
+			s.passing = 0
+		} else {
+			s.failing = 0
+		}
+	}
+
+	return ""
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+
+	return false
+}
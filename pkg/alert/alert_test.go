@@ -0,0 +1,214 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+func float64ptr(f float64) *float64 {
+	return &f
+}
+
+func TestStateUpdate(t *testing.T) {
+	ms := int64(time.Millisecond)
+	tests := []struct {
+		name     string
+		rule     Rule
+		losses   []uint64 // Lost probes out of 100 per bucket
+		expected []string
+	}{
+		{
+			name: "Test #1: fire and resolve immediately",
+			rule: Rule{
+				LossPercent: float64ptr(5),
+				Consecutive: 1,
+			},
+			losses:   []uint64{0, 5, 10, 4, 0},
+			expected: []string{"", statusFiring, "", statusResolved, ""},
+		},
+		{
+			name: "Test #2: consecutive buckets",
+			rule: Rule{
+				LossPercent: float64ptr(5),
+				Consecutive: 2,
+			},
+			losses:   []uint64{10, 0, 10, 10, 0, 10, 0, 0},
+			expected: []string{"", "", "", statusFiring, "", "", "", statusResolved},
+		},
+		{
+			name: "Test #3: for duration",
+			rule: Rule{
+				LossPercent: float64ptr(5),
+				Consecutive: 1,
+				For:         3 * time.Second,
+			},
+			losses:   []uint64{10, 10, 0, 10, 10, 10, 10},
+			expected: []string{"", "", "", "", "", statusFiring, ""},
+		},
+		{
+			name: "Test #4: hysteresis",
+			rule: Rule{
+				LossPercent: float64ptr(10),
+				Consecutive: 1,
+				Hysteresis:  0.5,
+			},
+			losses:   []uint64{7, 10, 7, 9, 5, 4},
+			expected: []string{"", statusFiring, "", "", "", statusResolved},
+		},
+	}
+
+	for _, test := range tests {
+		s := &state{}
+		for i, lost := range test.losses {
+			m := &measurement.Measurement{
+				Sent:     100,
+				Received: 100 - lost,
+			}
+
+			ts := int64(i) * 1000 * ms
+			status := s.update(&test.rule, test.rule.check(m), ts, ts+1000*ms)
+			assert.Equalf(t, test.expected[i], status, "%s: bucket %d", test.name, i)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	r := Rule{
+		Paths:   []string{"fra-*"},
+		Classes: []string{"EF"},
+	}
+
+	assert.True(t, r.matches("fra-ams", "EF"))
+	assert.False(t, r.matches("fra-ams", "BE"))
+	assert.False(t, r.matches("ams-fra", "EF"))
+	assert.True(t, (&Rule{}).matches("ams-fra", "BE"))
+}
+
+func newProber(t *testing.T) *prober.Prober {
+	p, err := prober.New(prober.Config{
+		PathName:            "a-b",
+		MeasurementLengthMS: 1000,
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		Hops: []prober.Hop{
+			{Name: "a"},
+			{Name: "b"},
+		},
+		StaticLabels: []prober.Label{
+			{Key: "site", Value: "fra"},
+		},
+	})
+	assert.NoError(t, err)
+
+	return p
+}
+
+func newWebhook(t *testing.T) (*httptest.Server, chan Notification) {
+	received := make(chan Notification, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := Notification{}
+		err := json.NewDecoder(r.Body).Decode(&n)
+		assert.NoError(t, err)
+		received <- n
+	}))
+
+	return srv, received
+}
+
+func TestWebhook(t *testing.T) {
+	srv, received := newWebhook(t)
+	defer srv.Close()
+
+	a := New(Config{
+		WebhookURL: srv.URL,
+		Timeout:    time.Second,
+		Rules: []Rule{
+			{
+				Name:        "loss",
+				LossPercent: float64ptr(5),
+				Consecutive: 1,
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	p := newProber(t)
+	a.Observe(p, 0, &measurement.Measurement{Sent: 100, Received: 50})
+	a.Observe(p, int64(time.Second), &measurement.Measurement{Sent: 100, Received: 100})
+
+	n := <-received
+	assert.Equal(t, Notification{
+		Status:   statusFiring,
+		Rule:     "loss",
+		Prober:   "a-b@BE",
+		Path:     "a-b",
+		Class:    "BE",
+		Hops:     "a-b",
+		Labels:   map[string]string{"site": "fra"},
+		StartsAt: time.Unix(0, 0).UTC(),
+		Values: Values{
+			LossPercent: 50,
+		},
+	}, n)
+
+	n = <-received
+	endsAt := time.Unix(2, 0).UTC()
+	assert.Equal(t, statusResolved, n.Status)
+	assert.Equal(t, time.Unix(0, 0).UTC(), n.StartsAt)
+	assert.Equal(t, &endsAt, n.EndsAt)
+}
+
+func TestForget(t *testing.T) {
+	srv, received := newWebhook(t)
+	defer srv.Close()
+
+	a := New(Config{
+		WebhookURL: srv.URL,
+		Timeout:    time.Second,
+		Rules: []Rule{
+			{
+				Name:        "loss",
+				LossPercent: float64ptr(5),
+				Consecutive: 1,
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	p := newProber(t)
+	a.Observe(p, 0, &measurement.Measurement{Sent: 100, Received: 50})
+	n := <-received
+	assert.Equal(t, statusFiring, n.Status)
+
+	a.Forget("c-d@BE")
+	a.Forget(p.ID())
+	n = <-received
+	assert.Equal(t, statusResolved, n.Status)
+	assert.Equal(t, "a-b@BE", n.Prober)
+	assert.Equal(t, map[string]string{"site": "fra"}, n.Labels)
+	assert.Equal(t, time.Unix(0, 0).UTC(), n.StartsAt)
+	assert.NotNil(t, n.EndsAt)
+	assert.Empty(t, a.states)
+
+	a.Forget(p.ID())
+	select {
+	case n := <-received:
+		t.Errorf("Unexpected notification after forgetting twice: %+v", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,137 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize  = 1000
+	maxRetries = 3
+	retryDelay = time.Second
+)
+
+// Notification is the JSON document posted to the webhook
+type Notification struct {
+	Status   string            `json:"status"`
+	Rule     string            `json:"rule"`
+	Prober   string            `json:"prober"`
+	Path     string            `json:"path"`
+	Class    string            `json:"class"`
+	Hops     string            `json:"hops"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	StartsAt time.Time         `json:"starts_at"`
+	EndsAt   *time.Time        `json:"ends_at,omitempty"`
+	Values   Values            `json:"values"`
+}
+
+// Values are the values of the bucket that caused a notification
+type Values struct {
+	LossPercent float64 `json:"loss_percent"`
+	RTTP99MS    float64 `json:"rtt_p99_ms"`
+	JitterMS    float64 `json:"jitter_ms"`
+}
+
+func newValues(m *measurement.Measurement) Values {
+	return Values{
+		LossPercent: m.Loss() * 100,
+		RTTP99MS:    float64(m.RTTQuantile(0.99)) / float64(time.Millisecond),
+		JitterMS:    float64(m.Jitter()) / float64(time.Millisecond),
+	}
+}
+
+func newNotification(status string, r *Rule, p *prober.Prober, s *state, end int64, m *measurement.Measurement) *Notification {
+	n := &Notification{
+		Status:   status,
+		Rule:     r.Name,
+		Prober:   p.ID(),
+		Path:     p.PathName(),
+		Class:    p.TOS().Name,
+		Hops:     p.Path(),
+		StartsAt: time.Unix(0, s.firingSince).UTC(),
+		Values:   newValues(m),
+	}
+
+	for _, l := range p.Config().StaticLabels {
+		if n.Labels == nil {
+			n.Labels = make(map[string]string)
+		}
+		n.Labels[l.Key] = l.Value
+	}
+
+	if status == statusResolved {
+		endsAt := time.Unix(0, end).UTC()
+		n.EndsAt = &endsAt
+	}
+
+	return n
+}
+
+// enqueue queues a notification without blocking the prober
+func (a *Alerter) enqueue(n *Notification) {
+	select {
+	case a.queue <- n:
+	default:
+		log.Errorf("Alert queue is full. Dropping notification for %q of prober %q", n.Rule, n.Prober)
+	}
+}
+
+func (a *Alerter) send(ctx context.Context, n *Notification) {
+	a.l.Lock()
+	url, timeout := a.cfg.WebhookURL, a.cfg.Timeout
+	a.l.Unlock()
+
+	body, err := json.Marshal(n)
+	if err != nil {
+		log.Errorf("Unable to marshal notification: %v", err)
+		return
+	}
+
+	for i := 0; i < maxRetries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+		}
+
+		err = a.post(ctx, url, timeout, body)
+		if err == nil {
+			return
+		}
+
+		log.Errorf("Unable to send alert notification (attempt %d/%d): %v", i+1, maxRetries, err)
+	}
+}
+
+func (a *Alerter) post(ctx context.Context, url string, timeout time.Duration, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Webhook returned %s", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/internal/perprober"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcapgo"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotFound is returned if a prober does not exist
+	ErrNotFound = perprober.ErrNotFound
+
+	// ErrActive is returned when starting a capture that is already active
+	ErrActive = perprober.ErrActive
+
+	// ErrInactive is returned when stopping a capture that is not active
+	ErrInactive = perprober.ErrInactive
+
+	// ErrLimit is returned when a capture exceeds the configured limits
+	ErrLimit = errors.New("Capture exceeds the configured limits")
+)
+
+// Config is the configuration of the capturer
+type Config struct {
+	Dir         string        // Directory the pcapng files are written to
+	MaxDuration time.Duration // Upper bound and default of the duration of a capture
+	MaxPackets  uint64        // Upper bound and default of the packets of a capture
+}
+
+// Capture describes an active capture
+type Capture struct {
+	Prober  string    `json:"prober"`
+	File    string    `json:"file"`
+	Packets uint64    `json:"packets"` // Sent and received packets after which the capture stops
+	Until   time.Time `json:"until"`
+}
+
+// Capturer writes the sent and received packets of selected probers into pcapng files.
+// Captures are started at runtime and stop after a duration or a number of packets.
+type Capturer struct {
+	cfg      Config
+	captures *perprober.Set[prober.CapturedPacket, *encoder]
+}
+
+// encoder writes the packets of a capture to its pcapng file
+type encoder struct {
+	info Capture
+	file *os.File
+	w    *pcapgo.NgWriter
+}
+
+// New creates a new capturer
+func New(cfg Config, probers perprober.ProberLister) *Capturer {
+	return &Capturer{
+		cfg:      cfg,
+		captures: perprober.NewSet[prober.CapturedPacket, *encoder]("capture", probers),
+	}
+}
+
+// Start starts capturing the packets of a prober. Zero values of d and packets select the configured maximum.
+func (c *Capturer) Start(id string, d time.Duration, packets uint64) (Capture, error) {
+	if d == 0 {
+		d = c.cfg.MaxDuration
+	}
+
+	if packets == 0 {
+		packets = c.cfg.MaxPackets
+	}
+
+	if d < 0 || d > c.cfg.MaxDuration || packets > c.cfg.MaxPackets {
+		return Capture{}, ErrLimit
+	}
+
+	enc, err := c.captures.Start(id, perprober.Limits{Duration: d, Items: packets}, func() (*encoder, error) {
+		err := os.MkdirAll(c.cfg.Dir, 0755)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create capture dir: %v", err)
+		}
+
+		now := time.Now().UTC()
+		path := c.path(id, now)
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create capture file: %v", err)
+		}
+
+		w, err := newPcapngWriter(f, id)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("Unable to write capture file: %v", err)
+		}
+
+		return &encoder{
+			info: Capture{
+				Prober:  id,
+				File:    path,
+				Packets: packets,
+				Until:   now.Add(d),
+			},
+			file: f,
+			w:    w,
+		}, nil
+	})
+	if err != nil {
+		return Capture{}, err
+	}
+
+	log.Infof("Started capturing packets of prober %q to %q", id, enc.info.File)
+	return enc.info, nil
+}
+
+// path returns the path of a capture file of a prober started at t
+func (c *Capturer) path(id string, t time.Time) string {
+	return filepath.Join(c.cfg.Dir, fmt.Sprintf("%s-%s.pcapng", perprober.FileName(id), t.Format("20060102T150405Z")))
+}
+
+// Stop stops capturing the packets of a prober. It blocks until all queued packets are written.
+func (c *Capturer) Stop(id string) error {
+	return c.captures.Stop(id)
+}
+
+// Forget stops the capture of a removed prober
+func (c *Capturer) Forget(id string) {
+	c.captures.Forget(id)
+}
+
+// Close stops all captures
+func (c *Capturer) Close() {
+	c.captures.Close()
+}
+
+// Active returns all active captures sorted by prober ID
+func (c *Capturer) Active() []Capture {
+	encs := c.captures.Encoders()
+	ret := make([]Capture, 0, len(encs))
+	for _, e := range encs {
+		ret = append(ret, e.info)
+	}
+
+	return ret
+}
+
+// Capturing returns whether the packets of a prober are captured
+func (c *Capturer) Capturing(p *prober.Prober) bool {
+	return c.captures.Active(p.ID())
+}
+
+// Capture queues a packet if its prober is captured
+func (c *Capturer) Capture(p *prober.Prober, pkt prober.CapturedPacket) {
+	pkt.Data = append([]byte(nil), pkt.Data...)
+	err := c.captures.Write(p.ID(), pkt)
+	if errors.Is(err, perprober.ErrQueueFull) {
+		log.Errorf("Capture queue of prober %q is full. Dropping packet of probe %d", p.ID(), pkt.Seq)
+	}
+}
+
+// Encode writes a packet to the interface of its direction
+func (e *encoder) Encode(pkt prober.CapturedPacket) error {
+	intf := ifSent
+	if pkt.Received {
+		intf = ifReceived
+	}
+
+	return e.w.WritePacket(gopacket.CaptureInfo{
+		Timestamp:      time.Unix(0, pkt.Ts),
+		CaptureLength:  len(pkt.Data),
+		Length:         len(pkt.Data),
+		InterfaceIndex: intf,
+	}, pkt.Data)
+}
+
+// Flush writes buffered packets to the file
+func (e *encoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Close flushes and closes the file
+func (e *encoder) Close() error {
+	err := e.w.Flush()
+	cerr := e.file.Close()
+	if err != nil {
+		return err
+	}
+
+	return cerr
+}
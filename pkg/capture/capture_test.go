@@ -0,0 +1,135 @@
+package capture
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLister []string
+
+func (f fakeLister) IDs() []string {
+	return f
+}
+
+func newProber(t *testing.T) *prober.Prober {
+	p, err := prober.New(prober.Config{
+		PathName: "a-b",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		SrcAddrs: []net.IP{net.ParseIP("169.254.0.1")},
+		Hops: []prober.Hop{
+			{Name: "a", DstRange: []net.IP{net.ParseIP("10.0.0.1")}},
+		},
+	})
+	assert.NoError(t, err)
+
+	return p
+}
+
+func TestCapturer(t *testing.T) {
+	p := newProber(t)
+	c := New(Config{
+		Dir:         t.TempDir(),
+		MaxDuration: time.Minute,
+		MaxPackets:  3,
+	}, fakeLister{"a-b@BE"})
+
+	_, err := c.Start("x@BE", 0, 0)
+	assert.Equal(t, ErrNotFound, err, "Test #1: unknown prober")
+	_, err = c.Start("a-b@BE", time.Hour, 0)
+	assert.Equal(t, ErrLimit, err, "Test #2: duration above limit")
+	_, err = c.Start("a-b@BE", 0, 4)
+	assert.Equal(t, ErrLimit, err, "Test #3: packets above limit")
+	assert.Equal(t, ErrInactive, c.Stop("a-b@BE"), "Test #4: stop inactive capture")
+
+	c.Capture(p, prober.CapturedPacket{Seq: 0, Data: []byte{0}})
+	assert.False(t, c.Capturing(p))
+
+	capt, err := c.Start("a-b@BE", 0, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), capt.Packets)
+	_, err = c.Start("a-b@BE", 0, 0)
+	assert.Equal(t, ErrActive, err, "Test #5: start active capture")
+	assert.True(t, c.Capturing(p))
+	assert.Equal(t, []Capture{capt}, c.Active())
+
+	data := []byte{0x45, 1, 2, 3, 4}
+	c.Capture(p, prober.CapturedPacket{Seq: 1, Ts: 1000, Data: data})
+	c.Capture(p, prober.CapturedPacket{Seq: 1, Ts: 2500, Received: true, Data: data[:4]})
+	assert.NoError(t, c.Stop("a-b@BE"))
+	assert.Empty(t, c.Active())
+
+	f, err := os.Open(capt.File)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	b, ci, err := r.ReadPacketData()
+	assert.NoError(t, err)
+	assert.Equal(t, data, b)
+	assert.Equal(t, 0, ci.InterfaceIndex)
+	assert.Equal(t, int64(1000), ci.Timestamp.UnixNano())
+
+	b, ci, err = r.ReadPacketData()
+	assert.NoError(t, err)
+	assert.Equal(t, data[:4], b)
+	assert.Equal(t, 1, ci.InterfaceIndex)
+	assert.Equal(t, int64(2500), ci.Timestamp.UnixNano())
+
+	_, _, err = r.ReadPacketData()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestCapturerPacketLimit(t *testing.T) {
+	p := newProber(t)
+	c := New(Config{
+		Dir:         t.TempDir(),
+		MaxDuration: time.Minute,
+		MaxPackets:  10,
+	}, fakeLister{"a-b@BE"})
+
+	capt, err := c.Start("a-b@BE", 0, 2)
+	assert.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		c.Capture(p, prober.CapturedPacket{Seq: uint64(i), Data: []byte{0x45, byte(i)}})
+	}
+
+	assert.Eventually(t, func() bool {
+		return !c.Capturing(p)
+	}, time.Second, 10*time.Millisecond)
+
+	f, err := os.Open(capt.File)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer f.Close()
+
+	r, err := pcapgo.NewNgReader(f, pcapgo.DefaultNgReaderOptions)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for i := 0; i < 2; i++ {
+		b, _, err := r.ReadPacketData()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0x45, byte(i)}, b)
+	}
+
+	_, _, err = r.ReadPacketData()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,51 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+)
+
+const (
+	ifSent     = 0
+	ifReceived = 1
+)
+
+// newPcapngWriter writes the section header of a capture and its two interfaces. Sent probes and returned
+// probes are written to separate interfaces
+func newPcapngWriter(w io.Writer, id string) (*pcapgo.NgWriter, error) {
+	ng, err := pcapgo.NewNgWriterInterface(w, pcapgo.NgInterface{
+		Name:     "sent",
+		Comment:  "Probes as crafted by the prober including the outer IPv4 header",
+		LinkType: layers.LinkTypeRaw,
+	}, pcapgo.NgWriterOptions{
+		SectionInfo: pcapgo.NgSectionInfo{
+			Hardware:    runtime.GOARCH,
+			OS:          runtime.GOOS,
+			Application: "matroschka-prober",
+			Comment:     fmt.Sprintf("Probes of prober %s", id),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to write section header: %v", err)
+	}
+
+	_, err = ng.AddInterface(pcapgo.NgInterface{
+		Name:     "received",
+		Comment:  "Returned probes. IPv4 and UDP headers are reconstructed from the socket metadata.",
+		LinkType: layers.LinkTypeRaw,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to add interface: %v", err)
+	}
+
+	err = ng.Flush()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to flush pcapng writer: %v", err)
+	}
+
+	return ng, nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"net/url"
+	"path"
+)
+
+var (
+	dfltAlertsTimeoutMS         = uint64(5000)
+	dfltAlertConsecutiveBuckets = uint64(1)
+	dfltAlertForMS              = uint64(0)
+	dfltAlertHysteresisPercent  = float64(0)
+	alertWebhookSchemes         = []string{"http", "https"}
+)
+
+// Alerts represents the alert rules and the webhook that is notified about firing and resolved alerts
+type Alerts struct {
+	WebhookURL string      `yaml:"webhook_url"`
+	TimeoutMS  *uint64     `yaml:"timeout_ms"`
+	Rules      []AlertRule `yaml:"rules"`
+}
+
+// AlertRule represents an alert rule. It applies to all paths and classes matching its selectors.
+// A bucket is failing if any of the configured thresholds is reached.
+type AlertRule struct {
+	Name               string   `yaml:"name"`
+	Paths              []string `yaml:"paths"` // Patterns as understood by path.Match. All paths if empty.
+	Classes            []string `yaml:"classes"`
+	LossPercent        *float64 `yaml:"loss_percent"`
+	RTTP99MS           *float64 `yaml:"rtt_p99_ms"`
+	JitterMS           *float64 `yaml:"jitter_ms"`
+	ConsecutiveBuckets *uint64  `yaml:"consecutive_buckets"` // Failing (or passing) buckets required to fire (or resolve)
+	ForMS              *uint64  `yaml:"for_ms"`              // Time the rule has to fail before firing
+	HysteresisPercent  *float64 `yaml:"hysteresis_percent"`  // Values must drop this much below the thresholds to resolve
+}
+
+func (a *Alerts) applyDefaults() {
+	if a.TimeoutMS == nil {
+		a.TimeoutMS = &dfltAlertsTimeoutMS
+	}
+
+	for i := range a.Rules {
+		a.Rules[i].applyDefaults()
+	}
+}
+
+func (r *AlertRule) applyDefaults() {
+	if r.ConsecutiveBuckets == nil {
+		r.ConsecutiveBuckets = &dfltAlertConsecutiveBuckets
+	}
+
+	if r.ForMS == nil {
+		r.ForMS = &dfltAlertForMS
+	}
+
+	if r.HysteresisPercent == nil {
+		r.HysteresisPercent = &dfltAlertHysteresisPercent
+	}
+}
+
+func (c *Config) validateAlerts(v *validator) {
+	if c.Alerts == nil {
+		return
+	}
+
+	u, err := url.Parse(c.Alerts.WebhookURL)
+	if err != nil || !stringIn(u.Scheme, alertWebhookSchemes) || u.Host == "" {
+		v.errorf("Invalid alert webhook URL %q", c.Alerts.WebhookURL)
+	}
+
+	if c.Alerts.TimeoutMS != nil && *c.Alerts.TimeoutMS == 0 {
+		v.errorf("Alert webhook timeout must be greater than 0")
+	}
+
+	seen := make(map[string]struct{})
+	for i := range c.Alerts.Rules {
+		r := &c.Alerts.Rules[i]
+		if r.Name == "" {
+			v.errorf("Alert rule #%d has no name", i)
+			continue
+		}
+
+		if _, ok := seen[r.Name]; ok {
+			v.errorf("Alert rule %q is defined more than once", r.Name)
+		}
+		seen[r.Name] = struct{}{}
+
+		c.validateAlertRule(v, r)
+	}
+}
+
+func (c *Config) validateAlertRule(v *validator, r *AlertRule) {
+	if r.LossPercent == nil && r.RTTP99MS == nil && r.JitterMS == nil {
+		v.errorf("Alert rule %q has no threshold", r.Name)
+	}
+
+	if r.LossPercent != nil && (*r.LossPercent <= 0 || *r.LossPercent > 100) {
+		v.errorf("Loss threshold of alert rule %q must be in range (0, 100]", r.Name)
+	}
+
+	if r.RTTP99MS != nil && *r.RTTP99MS <= 0 {
+		v.errorf("RTT p99 threshold of alert rule %q must be greater than 0", r.Name)
+	}
+
+	if r.JitterMS != nil && *r.JitterMS <= 0 {
+		v.errorf("Jitter threshold of alert rule %q must be greater than 0", r.Name)
+	}
+
+	if r.ConsecutiveBuckets != nil && *r.ConsecutiveBuckets == 0 {
+		v.errorf("Consecutive buckets of alert rule %q must be greater than 0", r.Name)
+	}
+
+	if h := r.HysteresisPercent; h != nil && (*h < 0 || *h >= 100) {
+		v.errorf("Hysteresis of alert rule %q must be in range [0, 100)", r.Name)
+	}
+
+	for _, pattern := range r.Paths {
+		if _, err := path.Match(pattern, ""); err != nil {
+			v.errorf("Invalid path pattern %q of alert rule %q: %v", pattern, r.Name, err)
+		}
+	}
+
+	for _, cl := range r.Classes {
+		if !c.classExists(cl) {
+			v.errorf("Class %q of alert rule %q does not exist", cl, r.Name)
+		}
+	}
+}
+
+func stringIn(needle string, haystack []string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,42 @@
+package config
+
+var (
+	dfltCaptureMaxDurationMS = uint64(60000)
+	dfltCaptureMaxPackets    = uint64(10000)
+)
+
+// Capture represents the settings of the packet capture. Captures are started per prober through the API
+// and stop after a duration or a number of packets.
+type Capture struct {
+	Dir           string  `yaml:"dir"`             // Directory the pcapng files are written to
+	MaxDurationMS *uint64 `yaml:"max_duration_ms"` // Upper bound and default of the duration of a capture
+	MaxPackets    *uint64 `yaml:"max_packets"`     // Upper bound and default of the packets of a capture
+}
+
+func (c *Capture) applyDefaults() {
+	if c.MaxDurationMS == nil {
+		c.MaxDurationMS = &dfltCaptureMaxDurationMS
+	}
+
+	if c.MaxPackets == nil {
+		c.MaxPackets = &dfltCaptureMaxPackets
+	}
+}
+
+func (c *Config) validateCapture(v *validator) {
+	if c.Capture == nil {
+		return
+	}
+
+	if c.Capture.Dir == "" {
+		v.errorf("Capture dir must be set")
+	}
+
+	if c.Capture.MaxDurationMS != nil && *c.Capture.MaxDurationMS == 0 {
+		v.errorf("Capture max duration must be greater than 0")
+	}
+
+	if c.Capture.MaxPackets != nil && *c.Capture.MaxPackets == 0 {
+		v.errorf("Capture max packets must be greater than 0")
+	}
+}
@@ -3,8 +3,10 @@ package config
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"net"
+	"os"
+	"sort"
+	"strings"
 
 	"github.com/exaring/matroschka-prober/pkg/prober"
 	"github.com/pkg/errors"
@@ -23,19 +25,102 @@ var (
 	dfltPPS                 = uint64(25)
 	dfltSrcRange            = "169.254.0.0/16"
 	dfltMetricsPath         = "/metrics"
+
+	dfltDiagnosticsLossThresholdPercent = float64(5)
+	dfltDiagnosticsDurationMS           = uint64(60000)
+	dfltDiagnosticsCooldownMS           = uint64(300000)
+
+	dfltHealthDegradedLossPercent = float64(1)
+	dfltHealthDownLossPercent     = float64(50)
+	dfltHealthHysteresisPercent   = float64(0)
+	dfltHealthConsecutiveBuckets  = uint64(1)
+
+	dfltEventsSize           = uint64(10000)
+	dfltEventsRTTStepPercent = float64(50)
+	dfltEventsRTTStepMinMS   = float64(1)
 )
 
 // Config represents the configuration of matroschka-prober
 type Config struct {
 	Version       string
-	MetricsPath   *string   `yaml:"metrcis_path"`
-	ListenAddress *string   `yaml:"listen_address"`
-	BasePort      *uint16   `yaml:"base_port"`
-	Defaults      *Defaults `yaml:"defaults"`
-	SrcRange      *string   `yaml:"src_range"`
-	Classes       []Class   `yaml:"classes"`
-	Paths         []Path    `yaml:"paths"`
-	Routers       []Router  `yaml:"routers"`
+	MetricsPath   *string           `yaml:"metrics_path"`
+	ListenAddress *string           `yaml:"listen_address"`
+	BasePort      *uint16           `yaml:"base_port"`
+	Defaults      *Defaults         `yaml:"defaults"`
+	SrcRange      *string           `yaml:"src_range"`
+	Classes       []Class           `yaml:"classes"`
+	Paths         []Path            `yaml:"paths"`
+	PathGroups    []PathGroup       `yaml:"path_groups"`
+	Routers       []Router          `yaml:"routers"`
+	Diagnostics   *Diagnostics      `yaml:"diagnostics"`
+	Labels        map[string]string `yaml:"labels"`
+	API           *API              `yaml:"api"`
+	Alerts        *Alerts           `yaml:"alerts"`
+	Health        *Health           `yaml:"health"`
+	Events        *Events           `yaml:"events"`
+	Segments      []Segment         `yaml:"segments"`
+	OTLP          *OTLP             `yaml:"otlp"`
+	InfluxDB      *InfluxDB         `yaml:"influxdb"`
+	FileSink      *FileSink         `yaml:"file_sink"`
+	ProbeRecorder *ProbeRecorder    `yaml:"probe_recorder"`
+	Capture       *Capture          `yaml:"capture"`
+
+	// MetricsPathDeprecated keeps configs working that use the misspelled key
+	MetricsPathDeprecated *string `yaml:"metrcis_path"`
+}
+
+// API represents the settings of the HTTP API. Changing probers through the API requires a token.
+type API struct {
+	Token                 *string `yaml:"token"`
+	TokenFile             *string `yaml:"token_file"`
+	UnauthenticatedReload *bool   `yaml:"unauthenticated_reload"` // Allow /-/reload without a token if none is configured
+}
+
+// AllowsUnauthenticatedReload returns whether /-/reload may be used without a token if none is configured
+func (a *API) AllowsUnauthenticatedReload() bool {
+	return a != nil && a.UnauthenticatedReload != nil && *a.UnauthenticatedReload
+}
+
+// GetToken returns the configured API token or an empty string if there is none
+func (a *API) GetToken() (string, error) {
+	if a.Token != nil {
+		return *a.Token, nil
+	}
+
+	if a.TokenFile == nil {
+		return "", nil
+	}
+
+	token, err := os.ReadFile(*a.TokenFile)
+	if err != nil {
+		return "", errors.Wrap(err, "Unable to read token file")
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+// Diagnostics represents the settings of the loss triggered sub-path diagnostics
+type Diagnostics struct {
+	LossThresholdPercent *float64 `yaml:"loss_threshold_percent"`
+	DurationMS           *uint64  `yaml:"duration_ms"`
+	CooldownMS           *uint64  `yaml:"cooldown_ms"`
+}
+
+// Health represents the thresholds the health state of a path is derived from
+type Health struct {
+	DegradedLossPercent *float64 `yaml:"degraded_loss_percent"`
+	DownLossPercent     *float64 `yaml:"down_loss_percent"`
+	DegradedRTTP99MS    *float64 `yaml:"degraded_rtt_p99_ms"`
+	HysteresisPercent   *float64 `yaml:"hysteresis_percent"`  // Values must drop this much below a threshold to recover
+	ConsecutiveBuckets  *uint64  `yaml:"consecutive_buckets"` // Buckets a new state must persist before it is taken
+}
+
+// Events represents the settings of the event history
+type Events struct {
+	Size           *uint64  `yaml:"size"`
+	File           *string  `yaml:"file"` // Events are persisted to this file if set
+	RTTStepPercent *float64 `yaml:"rtt_step_percent"`
+	RTTStepMinMS   *float64 `yaml:"rtt_step_min_ms"`
 }
 
 // Defaults represents the default section of the config
@@ -46,61 +131,61 @@ type Defaults struct {
 	SrcRange            *string `yaml:"src_range"`
 	TimeoutMS           *uint64 `yaml:"timeout"`
 	SrcInterface        *string `yaml:"src_interface"`
+	SrcAddr             *string `yaml:"src_addr"`
+	VRF                 *string `yaml:"vrf"`
+	FwMark              *uint32 `yaml:"fwmark"`
+	FwMarkReceive       *bool   `yaml:"fwmark_receive"`
+	NetNS               *string `yaml:"netns"`
 }
 
-// Class reperesnets a traffic class in the config file
+// Class reperesnets a traffic class in the config file. Settings of a class override the defaults
+// but are overridden by settings of a path.
 type Class struct {
-	Name string `yaml:"name"`
-	TOS  uint8  `yaml:"tos"`
+	Name                string  `yaml:"name"`
+	TOS                 uint8   `yaml:"tos"`
+	MeasurementLengthMS *uint64 `yaml:"measurement_length_ms"`
+	PayloadSizeBytes    *uint64 `yaml:"payload_size_bytes"`
+	PPS                 *uint64 `yaml:"pps"`
+	TimeoutMS           *uint64 `yaml:"timeout"`
+	FwMark              *uint32 `yaml:"fwmark"`
 }
 
 // Path represents a path to be probed
 type Path struct {
-	Name                string   `yaml:"name"`
-	Hops                []string `yaml:"hops"`
-	MeasurementLengthMS *uint64  `yaml:"measurement_length_ms"`
-	PayloadSizeBytes    *uint64  `yaml:"payload_size_bytes"`
-	PPS                 *uint64  `yaml:"pps"`
-	TimeoutMS           *uint64  `yaml:"timeout"`
+	Name                string            `yaml:"name"`
+	Hops                []string          `yaml:"hops"`
+	MeasurementLengthMS *uint64           `yaml:"measurement_length_ms"`
+	PayloadSizeBytes    *uint64           `yaml:"payload_size_bytes"`
+	PPS                 *uint64           `yaml:"pps"`
+	TimeoutMS           *uint64           `yaml:"timeout"`
+	SrcInterface        *string           `yaml:"src_interface"`
+	SrcAddr             *string           `yaml:"src_addr"`
+	VRF                 *string           `yaml:"vrf"`
+	FwMark              *uint32           `yaml:"fwmark"`
+	FwMarkReceive       *bool             `yaml:"fwmark_receive"`
+	NetNS               *string           `yaml:"netns"`
+	Labels              map[string]string `yaml:"labels"`
+	// Classes lists the names of the classes the path is probed with. All classes are used if empty.
+	Classes []string `yaml:"classes"`
+	// ResolvedClasses are the classes of the path with all settings resolved by ApplyDefaults
+	ResolvedClasses []Class `yaml:"-"`
 }
 
+// ProberNodeName is the name of the prober itself as start and end of every path. It is reserved and can not be used by routers.
+const ProberNodeName = "prober"
+
 // Router represents a router used a an explicit hop in a path
 type Router struct {
-	Name     string `yaml:"name"`
-	DstRange string `yaml:"dst_range"`
-	SrcRange string `yaml:"src_range"`
-}
-
-// Validate validates a configuration
-func (c *Config) Validate() error {
-	err := c.validatePaths()
-	if err != nil {
-		return fmt.Errorf("Path validation failed: %v", err)
-	}
-
-	err = c.validateRouters()
-	if err != nil {
-		return fmt.Errorf("Router validation failed: %v", err)
-	}
-
-	return nil
+	Name     string            `yaml:"name"`
+	DstRange string            `yaml:"dst_range"`
+	SrcRange string            `yaml:"src_range"`
+	Tags     []string          `yaml:"tags"`
+	Labels   map[string]string `yaml:"labels"`
 }
 
-func (c *Config) validatePaths() error {
-	for i := range c.Paths {
-		for j := range c.Paths[i].Hops {
-			if !c.routerExists(c.Paths[i].Hops[j]) {
-				return fmt.Errorf("Router %q of path %q does not exist", c.Paths[i].Hops[j], c.Paths[i].Name)
-			}
-		}
-	}
-
-	return nil
-}
-
-func (c *Config) routerExists(needle string) bool {
-	for i := range c.Routers {
-		if c.Routers[i].Name == needle {
+func (r *Router) hasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
 			return true
 		}
 	}
@@ -108,17 +193,6 @@ func (c *Config) routerExists(needle string) bool {
 	return false
 }
 
-func (c *Config) validateRouters() error {
-	for i := range c.Routers {
-		_, _, err := net.ParseCIDR(c.Routers[i].DstRange)
-		if err != nil {
-			return fmt.Errorf("Unable to parse dst IP range for router %q: %v", c.Routers[i].Name, err)
-		}
-	}
-
-	return nil
-}
-
 // ApplyDefaults applies default settings if they are missing from loaded config.
 func (c *Config) ApplyDefaults() {
 	if c.Defaults == nil {
@@ -130,6 +204,10 @@ func (c *Config) ApplyDefaults() {
 		c.SrcRange = c.Defaults.SrcRange
 	}
 
+	if c.MetricsPath == nil {
+		c.MetricsPath = c.MetricsPathDeprecated
+	}
+
 	if c.MetricsPath == nil {
 		c.MetricsPath = &dfltMetricsPath
 	}
@@ -142,7 +220,15 @@ func (c *Config) ApplyDefaults() {
 		c.BasePort = &dfltBasePort
 	}
 
+	if c.Classes == nil {
+		c.Classes = []Class{
+			dfltClass,
+		}
+	}
+
+	c.expandPathGroups()
 	for i := range c.Paths {
+		c.Paths[i].resolveClasses(c.Classes, c.Defaults)
 		c.Paths[i].applyDefaults(c.Defaults)
 	}
 
@@ -150,10 +236,88 @@ func (c *Config) ApplyDefaults() {
 		c.Routers[i].applyDefaults(c.Defaults)
 	}
 
-	if c.Classes == nil {
-		c.Classes = []Class{
-			dfltClass,
-		}
+	if c.Diagnostics != nil {
+		c.Diagnostics.applyDefaults()
+	}
+
+	if c.Alerts != nil {
+		c.Alerts.applyDefaults()
+	}
+
+	if c.OTLP != nil {
+		c.OTLP.applyDefaults()
+	}
+
+	if c.InfluxDB != nil {
+		c.InfluxDB.applyDefaults()
+	}
+
+	if c.FileSink != nil {
+		c.FileSink.applyDefaults()
+	}
+
+	if c.ProbeRecorder != nil {
+		c.ProbeRecorder.applyDefaults()
+	}
+
+	if c.Capture != nil {
+		c.Capture.applyDefaults()
+	}
+
+	if c.Health == nil {
+		c.Health = &Health{}
+	}
+	c.Health.applyDefaults()
+
+	if c.Events == nil {
+		c.Events = &Events{}
+	}
+	c.Events.applyDefaults()
+}
+
+func (d *Diagnostics) applyDefaults() {
+	if d.LossThresholdPercent == nil {
+		d.LossThresholdPercent = &dfltDiagnosticsLossThresholdPercent
+	}
+
+	if d.DurationMS == nil {
+		d.DurationMS = &dfltDiagnosticsDurationMS
+	}
+
+	if d.CooldownMS == nil {
+		d.CooldownMS = &dfltDiagnosticsCooldownMS
+	}
+}
+
+func (h *Health) applyDefaults() {
+	if h.DegradedLossPercent == nil {
+		h.DegradedLossPercent = &dfltHealthDegradedLossPercent
+	}
+
+	if h.DownLossPercent == nil {
+		h.DownLossPercent = &dfltHealthDownLossPercent
+	}
+
+	if h.HysteresisPercent == nil {
+		h.HysteresisPercent = &dfltHealthHysteresisPercent
+	}
+
+	if h.ConsecutiveBuckets == nil {
+		h.ConsecutiveBuckets = &dfltHealthConsecutiveBuckets
+	}
+}
+
+func (e *Events) applyDefaults() {
+	if e.Size == nil {
+		e.Size = &dfltEventsSize
+	}
+
+	if e.RTTStepPercent == nil {
+		e.RTTStepPercent = &dfltEventsRTTStepPercent
+	}
+
+	if e.RTTStepMinMS == nil {
+		e.RTTStepMinMS = &dfltEventsRTTStepMinMS
 	}
 }
 
@@ -163,6 +327,50 @@ func (r *Router) applyDefaults(d *Defaults) {
 	}
 }
 
+// resolveClasses resolves the settings of all classes used by a path. It must be called before
+// applyDefaults as settings explicitly set on the path take precedence over the class settings.
+func (p *Path) resolveClasses(classes []Class, d *Defaults) {
+	p.ResolvedClasses = make([]Class, 0, len(classes))
+	for i := range classes {
+		if !p.usesClass(classes[i].Name) {
+			continue
+		}
+
+		cl := classes[i]
+		cl.MeasurementLengthMS = resolveSetting(p.MeasurementLengthMS, cl.MeasurementLengthMS, d.MeasurementLengthMS)
+		cl.PayloadSizeBytes = resolveSetting(p.PayloadSizeBytes, cl.PayloadSizeBytes, d.PayloadSizeBytes)
+		cl.PPS = resolveSetting(p.PPS, cl.PPS, d.PPS)
+		cl.TimeoutMS = resolveSetting(p.TimeoutMS, cl.TimeoutMS, d.TimeoutMS)
+		cl.FwMark = resolveSetting(p.FwMark, cl.FwMark, d.FwMark)
+		p.ResolvedClasses = append(p.ResolvedClasses, cl)
+	}
+}
+
+func (p *Path) usesClass(name string) bool {
+	if len(p.Classes) == 0 {
+		return true
+	}
+
+	for _, c := range p.Classes {
+		if c == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolveSetting returns the first setting that is set
+func resolveSetting[T any](settings ...*T) *T {
+	for _, s := range settings {
+		if s != nil {
+			return s
+		}
+	}
+
+	return nil
+}
+
 func (p *Path) applyDefaults(d *Defaults) {
 	if p.MeasurementLengthMS == nil {
 		p.MeasurementLengthMS = d.MeasurementLengthMS
@@ -179,6 +387,26 @@ func (p *Path) applyDefaults(d *Defaults) {
 	if p.TimeoutMS == nil {
 		p.TimeoutMS = d.TimeoutMS
 	}
+
+	if p.SrcInterface == nil {
+		p.SrcInterface = d.SrcInterface
+	}
+
+	if p.SrcAddr == nil {
+		p.SrcAddr = d.SrcAddr
+	}
+
+	if p.VRF == nil {
+		p.VRF = d.VRF
+	}
+
+	if p.FwMarkReceive == nil {
+		p.FwMarkReceive = d.FwMarkReceive
+	}
+
+	if p.NetNS == nil {
+		p.NetNS = d.NetNS
+	}
 }
 
 func (d *Defaults) applyDefaults() {
@@ -203,13 +431,49 @@ func (d *Defaults) applyDefaults() {
 	}
 }
 
-// GetConfiguredSrcAddr gets an IPv4 address of the configured src interface
-func (c *Config) GetConfiguredSrcAddr() (net.IP, error) {
-	if c.Defaults.SrcInterface == nil {
+// GetConfiguredSrcAddr gets the configured src address of a path or, if none is configured,
+// an IPv4 address of the configured src interface (looked up in the network namespace of the path)
+func (p *Path) GetConfiguredSrcAddr() (net.IP, error) {
+	if p.SrcAddr != nil {
+		return net.ParseIP(*p.SrcAddr).To4(), nil
+	}
+
+	if p.SrcInterface == nil {
 		return nil, nil
 	}
 
-	return GetInterfaceAddr(*c.Defaults.SrcInterface)
+	var addr net.IP
+	err := prober.WithNetNS(p.netNS(), func() error {
+		var err error
+		addr, err = GetInterfaceAddr(*p.SrcInterface)
+		return err
+	})
+
+	return addr, err
+}
+
+func (p *Path) netNS() string {
+	if p.NetNS == nil {
+		return ""
+	}
+
+	return *p.NetNS
+}
+
+func (c *Class) fwMark() uint32 {
+	if c.FwMark == nil {
+		return 0
+	}
+
+	return *c.FwMark
+}
+
+func (p *Path) bindDevice() string {
+	if p.VRF == nil {
+		return ""
+	}
+
+	return *p.VRF
 }
 
 // GetInterfaceAddr gets an interface first IPv4 address
@@ -240,6 +504,101 @@ func GetInterfaceAddr(ifName string) (net.IP, error) {
 	return nil, nil
 }
 
+// ProberSpec describes the prober to be run for a path and class
+type ProberSpec struct {
+	Path   string
+	Class  string
+	Config prober.Config
+}
+
+// Key returns the key identifying the prober of a path and class across config reloads
+func (s *ProberSpec) Key() string {
+	return s.Path + "@" + s.Class
+}
+
+// ProberSpecs generates the prober specs for all paths and classes
+func (c *Config) ProberSpecs() ([]ProberSpec, error) {
+	ret := make([]ProberSpec, 0, len(c.Paths)*len(c.Classes))
+	for i := range c.Paths {
+		specs, err := c.pathProberSpecs(&c.Paths[i])
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, specs...)
+	}
+
+	return ret, nil
+}
+
+// NewPathProberSpecs generates the prober specs of a path that is not part of the config (e.g. added at runtime).
+// Defaults are applied to the path and it is validated against the config.
+func (c *Config) NewPathProberSpecs(p Path) ([]ProberSpec, error) {
+	p.resolveClasses(c.Classes, c.Defaults)
+	p.applyDefaults(c.Defaults)
+
+	v := &validator{}
+	if p.Name == "" {
+		v.errorf("Path has no name")
+	}
+
+	c.validatePath(v, &p)
+
+	// Label names must be known as all metrics of a family must have the same labels
+	keys := c.labelKeys()
+	for k := range p.Labels {
+		i := sort.SearchStrings(keys, k)
+		if i == len(keys) || keys[i] != k {
+			v.errorf("Label %q of path %q is not used in the config", k, p.Name)
+		}
+	}
+
+	if len(v.errs) > 0 {
+		return nil, &ValidationError{
+			Errors: v.errs,
+		}
+	}
+
+	return c.pathProberSpecs(&p)
+}
+
+func (c *Config) pathProberSpecs(p *Path) ([]ProberSpec, error) {
+	confSrc, err := p.GetConfiguredSrcAddr()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to get configured src addr of path %q", p.Name)
+	}
+
+	ret := make([]ProberSpec, 0, len(p.ResolvedClasses))
+	for _, cl := range p.ResolvedClasses {
+		ret = append(ret, ProberSpec{
+			Path:  p.Name,
+			Class: cl.Name,
+			Config: prober.Config{
+				PathName:          p.Name,
+				BasePort:          *c.BasePort,
+				ConfiguredSrcAddr: confSrc,
+				BindDevice:        p.bindDevice(),
+				FwMark:            cl.fwMark(),
+				FwMarkReceive:     p.FwMarkReceive != nil && *p.FwMarkReceive,
+				NetNS:             p.netNS(),
+				SrcAddrs:          GenerateAddrs(*c.SrcRange),
+				Hops:              c.PathToProberHops(*p),
+				StaticLabels:      c.staticLabels(p),
+				TOS: prober.TOS{
+					Name:  cl.Name,
+					Value: cl.TOS,
+				},
+				PPS:                 *cl.PPS,
+				PayloadSizeBytes:    *cl.PayloadSizeBytes,
+				MeasurementLengthMS: *cl.MeasurementLengthMS,
+				TimeoutMS:           *cl.TimeoutMS,
+			},
+		})
+	}
+
+	return ret, nil
+}
+
 // PathToProberHops generates prober hops
 func (c *Config) PathToProberHops(pathCfg Path) []prober.Hop {
 	res := make([]prober.Hop, 0)
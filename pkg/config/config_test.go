@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"github.com/exaring/matroschka-prober/pkg/prober"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -27,6 +28,17 @@ func TestConfigApplyDefaults(t *testing.T) {
 					SrcRange:            &dfltSrcRange,
 					TimeoutMS:           &dfltTimeoutMS,
 				},
+				Health: &Health{
+					DegradedLossPercent: &dfltHealthDegradedLossPercent,
+					DownLossPercent:     &dfltHealthDownLossPercent,
+					HysteresisPercent:   &dfltHealthHysteresisPercent,
+					ConsecutiveBuckets:  &dfltHealthConsecutiveBuckets,
+				},
+				Events: &Events{
+					Size:           &dfltEventsSize,
+					RTTStepPercent: &dfltEventsRTTStepPercent,
+					RTTStepMinMS:   &dfltEventsRTTStepMinMS,
+				},
 				Classes: []Class{
 					{
 						Name: "BE",
@@ -66,6 +78,17 @@ func TestConfigApplyDefaults(t *testing.T) {
 					SrcRange:            &dfltSrcRange,
 					TimeoutMS:           &dfltTimeoutMS,
 				},
+				Health: &Health{
+					DegradedLossPercent: &dfltHealthDegradedLossPercent,
+					DownLossPercent:     &dfltHealthDownLossPercent,
+					HysteresisPercent:   &dfltHealthHysteresisPercent,
+					ConsecutiveBuckets:  &dfltHealthConsecutiveBuckets,
+				},
+				Events: &Events{
+					Size:           &dfltEventsSize,
+					RTTStepPercent: &dfltEventsRTTStepPercent,
+					RTTStepMinMS:   &dfltEventsRTTStepMinMS,
+				},
 				Paths: []Path{
 					{
 						Name: "Some path test",
@@ -76,6 +99,16 @@ func TestConfigApplyDefaults(t *testing.T) {
 						PayloadSizeBytes:    &dfltPayloadSizeBytes,
 						PPS:                 &dfltPPS,
 						TimeoutMS:           &dfltTimeoutMS,
+						ResolvedClasses: []Class{
+							{
+								Name:                "BE",
+								TOS:                 0x00,
+								MeasurementLengthMS: &dfltMeasurementLengthMS,
+								PayloadSizeBytes:    &dfltPayloadSizeBytes,
+								PPS:                 &dfltPPS,
+								TimeoutMS:           &dfltTimeoutMS,
+							},
+						},
 					},
 				},
 				Routers: []Router{
@@ -100,3 +133,390 @@ func TestConfigApplyDefaults(t *testing.T) {
 		assert.Equal(t, test.expected, test.cfg, test.name)
 	}
 }
+
+func TestResolveClasses(t *testing.T) {
+	cfg := &Config{
+		Defaults: &Defaults{
+			PPS: uint64ptr(25),
+		},
+		Classes: []Class{
+			{Name: "BE", TOS: 0x00},
+			{Name: "EF", TOS: 0xb8, PPS: uint64ptr(5), TimeoutMS: uint64ptr(100), FwMark: uint32ptr(0x100)},
+			{Name: "AF41", TOS: 0x88},
+		},
+		Paths: []Path{
+			{Name: "all classes"},
+			{Name: "EF and BE", Classes: []string{"EF", "BE"}, PPS: uint64ptr(50)},
+		},
+	}
+	cfg.ApplyDefaults()
+
+	names := func(classes []Class) []string {
+		ret := make([]string, len(classes))
+		for i := range classes {
+			ret[i] = classes[i].Name
+		}
+		return ret
+	}
+
+	assert.Equal(t, []string{"BE", "EF", "AF41"}, names(cfg.Paths[0].ResolvedClasses))
+	assert.Equal(t, uint64(25), *cfg.Paths[0].ResolvedClasses[0].PPS, "defaults apply without overrides")
+	assert.Equal(t, uint64(5), *cfg.Paths[0].ResolvedClasses[1].PPS, "class overrides defaults")
+	assert.Equal(t, uint64(100), *cfg.Paths[0].ResolvedClasses[1].TimeoutMS, "class overrides defaults")
+
+	assert.Equal(t, []string{"BE", "EF"}, names(cfg.Paths[1].ResolvedClasses))
+	assert.Equal(t, uint64(50), *cfg.Paths[1].ResolvedClasses[1].PPS, "path overrides class")
+	assert.Equal(t, uint64(100), *cfg.Paths[1].ResolvedClasses[1].TimeoutMS, "class overrides defaults")
+	assert.Equal(t, uint32(0x100), *cfg.Paths[1].ResolvedClasses[1].FwMark, "class overrides defaults")
+	assert.Nil(t, cfg.Paths[1].ResolvedClasses[0].FwMark, "no fwmark by default")
+}
+
+func TestExpandPathGroups(t *testing.T) {
+	routers := []Router{
+		{Name: "core01.fra01", DstRange: "10.0.0.1/32", Tags: []string{"core"}},
+		{Name: "core02.fra01", DstRange: "10.0.0.2/32", Tags: []string{"core"}},
+		{Name: "edge01.fra01", DstRange: "10.0.0.3/32"},
+		{Name: "rr01.fra01", DstRange: "10.0.0.4/32"},
+	}
+
+	tests := []struct {
+		name     string
+		group    PathGroup
+		expected []string
+	}{
+		{
+			name: "Test #1: full mesh by name pattern",
+			group: PathGroup{
+				Path: Path{Name: "mesh"},
+				Type: pathGroupTypeFullMesh,
+				Routers: RouterSelector{
+					Names: []string{"*.fra01"},
+				},
+			},
+			expected: []string{
+				"mesh:core01.fra01-core02.fra01",
+				"mesh:core01.fra01-edge01.fra01",
+				"mesh:core01.fra01-rr01.fra01",
+				"mesh:core02.fra01-edge01.fra01",
+				"mesh:core02.fra01-rr01.fra01",
+				"mesh:edge01.fra01-rr01.fra01",
+			},
+		},
+		{
+			name: "Test #2: bidirectional hub and spoke by tag",
+			group: PathGroup{
+				Path: Path{Name: "hs"},
+				Type: pathGroupTypeHubAndSpoke,
+				Routers: RouterSelector{
+					Tags: []string{"core"},
+				},
+				Hubs: RouterSelector{
+					Names: []string{"core01.fra01"},
+				},
+				Bidirectional: true,
+			},
+			expected: []string{
+				"hs:core01.fra01-core02.fra01",
+				"hs:core02.fra01-core01.fra01",
+			},
+		},
+		{
+			name: "Test #3: via reflector",
+			group: PathGroup{
+				Path: Path{Name: "rr"},
+				Type: pathGroupTypeVia,
+				Via:  "rr01.fra01",
+			},
+			expected: []string{
+				"rr:rr01.fra01-core01.fra01",
+				"rr:rr01.fra01-core02.fra01",
+				"rr:rr01.fra01-edge01.fra01",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		cfg := &Config{
+			Routers:    routers,
+			PathGroups: []PathGroup{test.group},
+		}
+		cfg.ApplyDefaults()
+
+		names := make([]string, len(cfg.Paths))
+		for i := range cfg.Paths {
+			names[i] = cfg.Paths[i].Name
+			assert.Equal(t, dfltPPS, *cfg.Paths[i].PPS, test.name)
+		}
+
+		assert.Equal(t, test.expected, names, test.name)
+		assert.NoError(t, cfg.Validate(), test.name)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		expected []string
+	}{
+		{
+			name: "Test #1: valid config",
+			cfg: &Config{
+				Routers: []Router{
+					{Name: "core01.fra01", DstRange: "10.0.0.0/24"},
+				},
+				Paths: []Path{
+					{Name: "core01.fra01", Hops: []string{"core01.fra01"}},
+				},
+			},
+			expected: nil,
+		},
+		{
+			name: "Test #2: all errors are reported at once",
+			cfg: &Config{
+				SrcRange: strptr("169.254.0.0/8"),
+				Classes: []Class{
+					{Name: "BE"},
+					{Name: "BE", TOS: 0xb8},
+				},
+				Routers: []Router{
+					{Name: "core01.fra01", DstRange: "10.0.0.0/33"},
+					{Name: "core01.fra01", DstRange: "2001:db8::/64"},
+				},
+				Paths: []Path{
+					{
+						Name:                "p1",
+						Hops:                []string{"core01.fra01", "core02.fra01"},
+						PPS:                 uint64ptr(0),
+						MeasurementLengthMS: uint64ptr(100),
+						TimeoutMS:           uint64ptr(300),
+					},
+					{Name: "p1"},
+				},
+			},
+			expected: []string{
+				`Invalid src range: "169.254.0.0/8" exceeds the maximum of 65536 addresses`,
+				`Class "BE" is defined more than once`,
+				`Invalid dst range for router "core01.fra01": invalid CIDR address: 10.0.0.0/33`,
+				`Router "core01.fra01" is defined more than once`,
+				`Invalid dst range for router "core01.fra01": "2001:db8::/64" is not an IPv4 range`,
+				`Router "core02.fra01" of path "p1" does not exist`,
+				`PPS of path "p1" class "BE" must be greater than 0`,
+				`Timeout of path "p1" class "BE" must be less than 3 times the measurement length`,
+				`PPS of path "p1" class "BE" must be greater than 0`,
+				`Timeout of path "p1" class "BE" must be less than 3 times the measurement length`,
+				`Path "p1" is defined more than once`,
+				`Path "p1" has no hops`,
+			},
+		},
+		{
+			name: "Test #3: alert rules",
+			cfg: &Config{
+				Alerts: &Alerts{
+					WebhookURL: "ftp://example.com",
+					Rules: []AlertRule{
+						{Name: "loss", LossPercent: float64ptr(5), Classes: []string{"EF"}},
+						{Name: "loss", RTTP99MS: float64ptr(50), HysteresisPercent: float64ptr(100)},
+						{Name: "none", Paths: []string{"["}},
+					},
+				},
+			},
+			expected: []string{
+				`Invalid alert webhook URL "ftp://example.com"`,
+				`Class "EF" of alert rule "loss" does not exist`,
+				`Alert rule "loss" is defined more than once`,
+				`Hysteresis of alert rule "loss" must be in range [0, 100)`,
+				`Alert rule "none" has no threshold`,
+				`Invalid path pattern "[" of alert rule "none": syntax error in pattern`,
+			},
+		},
+		{
+			name: "Test #4: segments",
+			cfg: &Config{
+				Classes: []Class{
+					{Name: "BE"},
+					{Name: "EF", TOS: 0xb8},
+				},
+				Routers: []Router{
+					{Name: "a", DstRange: "10.0.0.0/32"},
+					{Name: "b", DstRange: "10.0.0.1/32"},
+				},
+				Paths: []Path{
+					{Name: "a-b", Hops: []string{"a", "b"}, Classes: []string{"BE"}},
+					{Name: "a", Hops: []string{"a"}, Classes: []string{"EF"}},
+					{Name: "b", Hops: []string{"b"}, MeasurementLengthMS: uint64ptr(2000)},
+				},
+				Segments: []Segment{
+					{Name: "b", Path: "a-b", Subtract: "a"},
+					{Name: "b", Path: "a-b", Subtract: "a-b"},
+					{Name: "c", Path: "a-b", Subtract: "b"},
+					{Name: "d", Path: "a-b-c", Subtract: "a-b"},
+				},
+			},
+			expected: []string{
+				`Paths of segment "b" have no class in common`,
+				`Segment "b" is defined more than once`,
+				`Segment "b" subtracts path "a-b" from itself`,
+				`Paths of segment "c" use different measurement lengths for class "BE"`,
+				`Path "a-b-c" of segment "d" does not exist`,
+			},
+		},
+		{
+			name: "Test #5: OTLP exporter",
+			cfg: &Config{
+				OTLP: &OTLP{
+					Protocol:  strptr("udp"),
+					TimeoutMS: uint64ptr(0),
+				},
+			},
+			expected: []string{
+				`OTLP endpoint must be set`,
+				`Invalid OTLP protocol "udp"`,
+				`OTLP timeout must be greater than 0`,
+			},
+		},
+		{
+			name: "Test #6: InfluxDB sink",
+			cfg: &Config{
+				InfluxDB: &InfluxDB{
+					URL:        "influx:8086",
+					UDPAddress: "influx",
+					BatchSize:  uint64ptr(0),
+				},
+			},
+			expected: []string{
+				`Exactly one of InfluxDB URL and UDP address must be set`,
+				`Invalid InfluxDB URL "influx:8086"`,
+				`Invalid InfluxDB UDP address "influx": address influx: missing port in address`,
+				`InfluxDB batch size must be greater than 0`,
+			},
+		},
+		{
+			name: "Test #7: file sink",
+			cfg: &Config{
+				FileSink: &FileSink{
+					MaxSizeMB: uint64ptr(0),
+				},
+			},
+			expected: []string{
+				`File sink path must be set`,
+				`File sink max size must be greater than 0`,
+			},
+		},
+		{
+			name: "Test #8: probe recorder",
+			cfg: &Config{
+				ProbeRecorder: &ProbeRecorder{},
+			},
+			expected: []string{
+				`Probe recorder dir must be set`,
+			},
+		},
+		{
+			name: "Test #9: packet capture",
+			cfg: &Config{
+				Capture: &Capture{
+					MaxPackets: uint64ptr(0),
+				},
+			},
+			expected: []string{
+				`Capture dir must be set`,
+				`Capture max packets must be greater than 0`,
+			},
+		},
+		{
+			name: "Test #10: reserved router name",
+			cfg: &Config{
+				Routers: []Router{
+					{Name: "prober", DstRange: "10.0.0.0/32"},
+				},
+			},
+			expected: []string{
+				`Router name "prober" is reserved for the prober itself`,
+			},
+		},
+		{
+			name: "Test #11: PPS limit",
+			cfg: &Config{
+				Routers: []Router{
+					{Name: "a", DstRange: "10.0.0.0/32"},
+				},
+				Paths: []Path{
+					{Name: "a", Hops: []string{"a"}, PPS: uint64ptr(2000000000)},
+					{Name: "b", Hops: []string{"a"}, PPS: uint64ptr(100000)},
+				},
+			},
+			expected: []string{
+				`PPS of path "a" class "BE" must not exceed 100000`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test.cfg.ApplyDefaults()
+		err := test.cfg.Validate()
+		if test.expected == nil {
+			assert.NoError(t, err, test.name)
+			continue
+		}
+
+		verr, ok := err.(*ValidationError)
+		if !assert.True(t, ok, test.name) {
+			continue
+		}
+
+		msgs := make([]string, len(verr.Errors))
+		for i := range verr.Errors {
+			msgs[i] = verr.Errors[i].Error()
+		}
+
+		assert.Equal(t, test.expected, msgs, test.name)
+	}
+}
+
+func strptr(s string) *string {
+	return &s
+}
+
+func uint64ptr(v uint64) *uint64 {
+	return &v
+}
+
+func uint32ptr(v uint32) *uint32 {
+	return &v
+}
+
+func TestStaticLabels(t *testing.T) {
+	cfg := &Config{
+		Labels: map[string]string{
+			"region": "eu",
+			"sla":    "bronze",
+		},
+		Routers: []Router{
+			{Name: "core01.fra01", Labels: map[string]string{"site": "fra01"}},
+			{Name: "core01.ams01", Labels: map[string]string{"site": "ams01", "region": "eu-west"}},
+		},
+		Paths: []Path{
+			{Name: "p1", Hops: []string{"core01.fra01", "core01.ams01"}, Labels: map[string]string{"sla": "gold"}},
+			{Name: "p2", Hops: []string{"core01.fra01"}, Labels: map[string]string{"customer": "acme"}},
+		},
+	}
+
+	assert.Equal(t, []prober.Label{
+		{Key: "customer", Value: ""},
+		{Key: "region", Value: "eu-west"},
+		{Key: "site", Value: "ams01"},
+		{Key: "sla", Value: "gold"},
+	}, cfg.staticLabels(&cfg.Paths[0]))
+
+	assert.Equal(t, []prober.Label{
+		{Key: "customer", Value: "acme"},
+		{Key: "region", Value: "eu"},
+		{Key: "site", Value: "fra01"},
+		{Key: "sla", Value: "bronze"},
+	}, cfg.staticLabels(&cfg.Paths[1]))
+}
+
+func float64ptr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,37 @@
+package config
+
+var (
+	dfltFileSinkMaxSizeMB = uint64(100)
+	dfltFileSinkMaxFiles  = uint64(10)
+)
+
+// FileSink represents the settings of the file sink. Every finished bucket is written as JSON line.
+type FileSink struct {
+	Path      string  `yaml:"path"`
+	MaxSizeMB *uint64 `yaml:"max_size_mb"` // Size after which the file is rotated
+	MaxFiles  *uint64 `yaml:"max_files"`   // Rotated files that are kept. 0 keeps none.
+}
+
+func (f *FileSink) applyDefaults() {
+	if f.MaxSizeMB == nil {
+		f.MaxSizeMB = &dfltFileSinkMaxSizeMB
+	}
+
+	if f.MaxFiles == nil {
+		f.MaxFiles = &dfltFileSinkMaxFiles
+	}
+}
+
+func (c *Config) validateFileSink(v *validator) {
+	if c.FileSink == nil {
+		return
+	}
+
+	if c.FileSink.Path == "" {
+		v.errorf("File sink path must be set")
+	}
+
+	if c.FileSink.MaxSizeMB != nil && *c.FileSink.MaxSizeMB == 0 {
+		v.errorf("File sink max size must be greater than 0")
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"net"
+	"net/url"
+)
+
+var (
+	dfltInfluxDBMeasurement     = "matroschka"
+	dfltInfluxDBBatchSize       = uint64(1000)
+	dfltInfluxDBFlushIntervalMS = uint64(1000)
+	dfltInfluxDBTimeoutMS       = uint64(5000)
+	dfltInfluxDBRetries         = uint64(3)
+)
+
+// InfluxDB represents the settings of the InfluxDB sink. Every finished bucket is written as a point
+// in line protocol to either an HTTP write endpoint or a UDP socket.
+type InfluxDB struct {
+	URL             string  `yaml:"url"`         // HTTP write endpoint including database or org and bucket parameters
+	UDPAddress      string  `yaml:"udp_address"` // host:port of the UDP listener
+	Token           *string `yaml:"token"`       // Sent as Authorization header if set
+	Measurement     *string `yaml:"measurement"`
+	BatchSize       *uint64 `yaml:"batch_size"`
+	FlushIntervalMS *uint64 `yaml:"flush_interval_ms"` // Maximum time points are held back to fill a batch
+	TimeoutMS       *uint64 `yaml:"timeout_ms"`
+	Retries         *uint64 `yaml:"retries"` // Retries of failed HTTP writes
+}
+
+func (i *InfluxDB) applyDefaults() {
+	if i.Measurement == nil {
+		i.Measurement = &dfltInfluxDBMeasurement
+	}
+
+	if i.BatchSize == nil {
+		i.BatchSize = &dfltInfluxDBBatchSize
+	}
+
+	if i.FlushIntervalMS == nil {
+		i.FlushIntervalMS = &dfltInfluxDBFlushIntervalMS
+	}
+
+	if i.TimeoutMS == nil {
+		i.TimeoutMS = &dfltInfluxDBTimeoutMS
+	}
+
+	if i.Retries == nil {
+		i.Retries = &dfltInfluxDBRetries
+	}
+}
+
+func (c *Config) validateInfluxDB(v *validator) {
+	i := c.InfluxDB
+	if i == nil {
+		return
+	}
+
+	if (i.URL == "") == (i.UDPAddress == "") {
+		v.errorf("Exactly one of InfluxDB URL and UDP address must be set")
+	}
+
+	if i.URL != "" {
+		u, err := url.Parse(i.URL)
+		if err != nil || !stringIn(u.Scheme, []string{"http", "https"}) || u.Host == "" {
+			v.errorf("Invalid InfluxDB URL %q", i.URL)
+		}
+	}
+
+	if i.UDPAddress != "" {
+		if _, _, err := net.SplitHostPort(i.UDPAddress); err != nil {
+			v.errorf("Invalid InfluxDB UDP address %q: %v", i.UDPAddress, err)
+		}
+	}
+
+	if i.Measurement != nil && *i.Measurement == "" {
+		v.errorf("InfluxDB measurement must not be empty")
+	}
+
+	if i.BatchSize != nil && *i.BatchSize == 0 {
+		v.errorf("InfluxDB batch size must be greater than 0")
+	}
+
+	if i.FlushIntervalMS != nil && *i.FlushIntervalMS == 0 {
+		v.errorf("InfluxDB flush interval must be greater than 0")
+	}
+
+	if i.TimeoutMS != nil && *i.TimeoutMS == 0 {
+		v.errorf("InfluxDB timeout must be greater than 0")
+	}
+}
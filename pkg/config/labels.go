@@ -0,0 +1,98 @@
+package config
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/exaring/matroschka-prober/pkg/prober"
+)
+
+var (
+	labelNameRegexp = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+
+	// reservedLabels are set by the prober itself
+	reservedLabels = map[string]struct{}{
+		"path":  {},
+		"tos":   {},
+		"state": {},
+	}
+)
+
+// staticLabels returns the static labels of a path. Labels of the routers along the path override
+// global labels (later hops win) and labels of the path itself override both. As all metrics of
+// a family must have the same label names, every label known to the config is returned, empty if unset.
+func (c *Config) staticLabels(p *Path) []prober.Label {
+	values := make(map[string]string)
+	for k, v := range c.Labels {
+		values[k] = v
+	}
+
+	for _, hop := range p.Hops {
+		for i := range c.Routers {
+			if c.Routers[i].Name != hop {
+				continue
+			}
+
+			for k, v := range c.Routers[i].Labels {
+				values[k] = v
+			}
+		}
+	}
+
+	for k, v := range p.Labels {
+		values[k] = v
+	}
+
+	keys := c.labelKeys()
+	ret := make([]prober.Label, len(keys))
+	for i, k := range keys {
+		ret[i] = prober.Label{
+			Key:   k,
+			Value: values[k],
+		}
+	}
+
+	return ret
+}
+
+// labelKeys returns the sorted names of all labels defined anywhere in the config
+func (c *Config) labelKeys() []string {
+	keys := make(map[string]struct{})
+	for k := range c.Labels {
+		keys[k] = struct{}{}
+	}
+
+	for i := range c.Routers {
+		for k := range c.Routers[i].Labels {
+			keys[k] = struct{}{}
+		}
+	}
+
+	for i := range c.Paths {
+		for k := range c.Paths[i].Labels {
+			keys[k] = struct{}{}
+		}
+	}
+
+	ret := make([]string, 0, len(keys))
+	for k := range keys {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
+
+func validateLabels(v *validator, labels map[string]string, owner string) {
+	for k := range labels {
+		if !labelNameRegexp.MatchString(k) || strings.HasPrefix(k, "__") {
+			v.errorf("Invalid label name %q of %s", k, owner)
+			continue
+		}
+
+		if _, ok := reservedLabels[k]; ok {
+			v.errorf("Label name %q of %s is reserved", k, owner)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+var (
+	dfltOTLPProtocol  = "grpc"
+	dfltOTLPInsecure  = false
+	dfltOTLPTimeoutMS = uint64(10000)
+	otlpProtocols     = []string{"grpc", "http"}
+)
+
+// OTLP represents the settings of the OpenTelemetry metrics exporter. Measurements are pushed to the
+// endpoint after every finished bucket.
+type OTLP struct {
+	Endpoint  string            `yaml:"endpoint"` // host:port of the receiver
+	Protocol  *string           `yaml:"protocol"` // grpc or http
+	Insecure  *bool             `yaml:"insecure"` // Disables TLS
+	Headers   map[string]string `yaml:"headers"`
+	TimeoutMS *uint64           `yaml:"timeout_ms"`
+}
+
+func (o *OTLP) applyDefaults() {
+	if o.Protocol == nil {
+		o.Protocol = &dfltOTLPProtocol
+	}
+
+	if o.Insecure == nil {
+		o.Insecure = &dfltOTLPInsecure
+	}
+
+	if o.TimeoutMS == nil {
+		o.TimeoutMS = &dfltOTLPTimeoutMS
+	}
+}
+
+func (c *Config) validateOTLP(v *validator) {
+	if c.OTLP == nil {
+		return
+	}
+
+	if c.OTLP.Endpoint == "" {
+		v.errorf("OTLP endpoint must be set")
+	}
+
+	if c.OTLP.Protocol != nil && !stringIn(*c.OTLP.Protocol, otlpProtocols) {
+		v.errorf("Invalid OTLP protocol %q", *c.OTLP.Protocol)
+	}
+
+	if c.OTLP.TimeoutMS != nil && *c.OTLP.TimeoutMS == 0 {
+		v.errorf("OTLP timeout must be greater than 0")
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+const (
+	pathGroupTypeFullMesh    = "full_mesh"
+	pathGroupTypeHubAndSpoke = "hub_and_spoke"
+	pathGroupTypeVia         = "via"
+)
+
+// PathGroup generates paths between the routers matched by its selectors.
+// All path settings besides name and hops are inherited by the generated paths.
+type PathGroup struct {
+	Path          `yaml:",inline"`
+	Type          string         `yaml:"type"`
+	Routers       RouterSelector `yaml:"routers"`
+	Hubs          RouterSelector `yaml:"hubs"`
+	Via           string         `yaml:"via"`
+	Bidirectional bool           `yaml:"bidirectional"`
+}
+
+// RouterSelector selects routers by name patterns (see path.Match) or tags. An empty selector matches all routers.
+type RouterSelector struct {
+	Names []string `yaml:"names"`
+	Tags  []string `yaml:"tags"`
+}
+
+func (s *RouterSelector) matches(r *Router) bool {
+	if len(s.Names) == 0 && len(s.Tags) == 0 {
+		return true
+	}
+
+	for _, pattern := range s.Names {
+		if ok, _ := path.Match(pattern, r.Name); ok {
+			return true
+		}
+	}
+
+	for _, t := range s.Tags {
+		if r.hasTag(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *RouterSelector) validate() error {
+	for _, pattern := range s.Names {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("Invalid router name pattern %q: %v", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) selectRouters(s *RouterSelector) []string {
+	ret := make([]string, 0)
+	for i := range c.Routers {
+		if s.matches(&c.Routers[i]) {
+			ret = append(ret, c.Routers[i].Name)
+		}
+	}
+
+	return ret
+}
+
+// expandPathGroups appends the paths generated by all path groups to the paths
+func (c *Config) expandPathGroups() {
+	for i := range c.PathGroups {
+		for _, hops := range c.pathGroupHops(&c.PathGroups[i]) {
+			p := c.PathGroups[i].Path
+			p.Name = fmt.Sprintf("%s:%s", c.PathGroups[i].Name, strings.Join(hops, "-"))
+			p.Hops = hops
+			c.Paths = append(c.Paths, p)
+		}
+	}
+}
+
+func (c *Config) pathGroupHops(g *PathGroup) [][]string {
+	routers := c.selectRouters(&g.Routers)
+	ret := make([][]string, 0)
+
+	switch g.Type {
+	case pathGroupTypeFullMesh:
+		for i := range routers {
+			for j := i + 1; j < len(routers); j++ {
+				ret = append(ret, []string{routers[i], routers[j]})
+				if g.Bidirectional {
+					ret = append(ret, []string{routers[j], routers[i]})
+				}
+			}
+		}
+	case pathGroupTypeHubAndSpoke:
+		for _, hub := range c.selectRouters(&g.Hubs) {
+			for _, spoke := range routers {
+				if spoke == hub {
+					continue
+				}
+
+				ret = append(ret, []string{hub, spoke})
+				if g.Bidirectional {
+					ret = append(ret, []string{spoke, hub})
+				}
+			}
+		}
+	case pathGroupTypeVia:
+		for _, r := range routers {
+			if r == g.Via {
+				continue
+			}
+
+			ret = append(ret, []string{g.Via, r})
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,38 @@
+package config
+
+var (
+	dfltProbeRecorderMaxSizeMB = uint64(100)
+	dfltProbeRecorderMaxFiles  = uint64(10)
+)
+
+// ProbeRecorder represents the settings of the raw probe recorder. Recording is started and stopped
+// per prober through the API.
+type ProbeRecorder struct {
+	Dir       string  `yaml:"dir"`         // Directory the CSV files are written to
+	MaxSizeMB *uint64 `yaml:"max_size_mb"` // Size after which a file is rotated
+	MaxFiles  *uint64 `yaml:"max_files"`   // Rotated files that are kept per prober. 0 keeps none.
+}
+
+func (r *ProbeRecorder) applyDefaults() {
+	if r.MaxSizeMB == nil {
+		r.MaxSizeMB = &dfltProbeRecorderMaxSizeMB
+	}
+
+	if r.MaxFiles == nil {
+		r.MaxFiles = &dfltProbeRecorderMaxFiles
+	}
+}
+
+func (c *Config) validateProbeRecorder(v *validator) {
+	if c.ProbeRecorder == nil {
+		return
+	}
+
+	if c.ProbeRecorder.Dir == "" {
+		v.errorf("Probe recorder dir must be set")
+	}
+
+	if c.ProbeRecorder.MaxSizeMB != nil && *c.ProbeRecorder.MaxSizeMB == 0 {
+		v.errorf("Probe recorder max size must be greater than 0")
+	}
+}
@@ -0,0 +1,78 @@
+package config
+
+// Segment represents a segment whose RTT is derived as the difference of the RTTs of two paths,
+// e.g. the segment B-C-B as the difference of the paths A-B-C-B-A and A-B-A
+type Segment struct {
+	Name     string `yaml:"name"`
+	Path     string `yaml:"path"`
+	Subtract string `yaml:"subtract"` // Path whose RTT is subtracted from the RTT of Path
+}
+
+func (c *Config) validateSegments(v *validator) {
+	seen := make(map[string]struct{})
+	for i := range c.Segments {
+		s := &c.Segments[i]
+		if s.Name == "" {
+			v.errorf("Segment #%d has no name", i)
+			continue
+		}
+
+		if _, ok := seen[s.Name]; ok {
+			v.errorf("Segment %q is defined more than once", s.Name)
+		}
+		seen[s.Name] = struct{}{}
+
+		c.validateSegment(v, s)
+	}
+}
+
+func (c *Config) validateSegment(v *validator, s *Segment) {
+	if s.Path == s.Subtract {
+		v.errorf("Segment %q subtracts path %q from itself", s.Name, s.Path)
+		return
+	}
+
+	p := c.path(s.Path)
+	if p == nil {
+		v.errorf("Path %q of segment %q does not exist", s.Path, s.Name)
+	}
+
+	sub := c.path(s.Subtract)
+	if sub == nil {
+		v.errorf("Path %q of segment %q does not exist", s.Subtract, s.Name)
+	}
+
+	if p == nil || sub == nil {
+		return
+	}
+
+	// Buckets can only be aligned if both paths use the same measurement length
+	shared := 0
+	for i := range p.ResolvedClasses {
+		for j := range sub.ResolvedClasses {
+			a, b := &p.ResolvedClasses[i], &sub.ResolvedClasses[j]
+			if a.Name != b.Name {
+				continue
+			}
+
+			shared++
+			if a.MeasurementLengthMS != nil && b.MeasurementLengthMS != nil && *a.MeasurementLengthMS != *b.MeasurementLengthMS {
+				v.errorf("Paths of segment %q use different measurement lengths for class %q", s.Name, a.Name)
+			}
+		}
+	}
+
+	if shared == 0 {
+		v.errorf("Paths of segment %q have no class in common", s.Name)
+	}
+}
+
+func (c *Config) path(name string) *Path {
+	for i := range c.Paths {
+		if c.Paths[i].Name == name {
+			return &c.Paths[i]
+		}
+	}
+
+	return nil
+}
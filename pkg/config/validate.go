@@ -0,0 +1,362 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// maxRangeHostBits limits src and dst ranges to 65536 addresses
+	maxRangeHostBits = 16
+
+	// maxPPS limits the probe rate of a prober. The sender ticks every 10µs at this rate.
+	maxPPS = 100000
+
+	// transitTimeoutFactor is the number of measurement lengths after which probes in transit are dropped
+	transitTimeoutFactor = 3
+)
+
+// ValidationError contains all problems found in a configuration
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		msgs[i] = e.Errors[i].Error()
+	}
+
+	return fmt.Sprintf("%d config error(s): %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// Validate validates a configuration. It must be called after ApplyDefaults and reports all problems at once.
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	c.validateGlobal(v)
+	c.validateClasses(v)
+	c.validateRouters(v)
+	c.validatePaths(v)
+	c.validatePathGroups(v)
+	c.validateDiagnostics(v)
+	c.validateAlerts(v)
+	c.validateHealth(v)
+	c.validateEvents(v)
+	c.validateSegments(v)
+	c.validateOTLP(v)
+	c.validateInfluxDB(v)
+	c.validateFileSink(v)
+	c.validateProbeRecorder(v)
+	c.validateCapture(v)
+
+	if len(v.errs) > 0 {
+		return &ValidationError{
+			Errors: v.errs,
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) validateGlobal(v *validator) {
+	if c.MetricsPath != nil && !strings.HasPrefix(*c.MetricsPath, "/") {
+		v.errorf("Metrics path %q must start with /", *c.MetricsPath)
+	}
+
+	if c.BasePort != nil && *c.BasePort == 0 {
+		v.errorf("Base port must not be 0")
+	}
+
+	if c.SrcRange != nil {
+		if err := validateRange(*c.SrcRange); err != nil {
+			v.errorf("Invalid src range: %v", err)
+		}
+	}
+
+	validateLabels(v, c.Labels, "global labels")
+
+	if c.API != nil && c.API.Token != nil && c.API.TokenFile != nil {
+		v.errorf("Only one of API token and API token file may be set")
+	}
+}
+
+func (c *Config) validateClasses(v *validator) {
+	seen := make(map[string]struct{})
+	for i := range c.Classes {
+		cl := &c.Classes[i]
+		if cl.Name == "" {
+			v.errorf("Class #%d has no name", i)
+			continue
+		}
+
+		if _, ok := seen[cl.Name]; ok {
+			v.errorf("Class %q is defined more than once", cl.Name)
+		}
+		seen[cl.Name] = struct{}{}
+	}
+}
+
+func (c *Config) validateRouters(v *validator) {
+	seen := make(map[string]struct{})
+	for i := range c.Routers {
+		r := &c.Routers[i]
+		if r.Name == "" {
+			v.errorf("Router #%d has no name", i)
+			continue
+		}
+
+		if _, ok := seen[r.Name]; ok {
+			v.errorf("Router %q is defined more than once", r.Name)
+		}
+		seen[r.Name] = struct{}{}
+
+		if r.Name == ProberNodeName {
+			v.errorf("Router name %q is reserved for the prober itself", r.Name)
+		}
+
+		if err := validateRange(r.DstRange); err != nil {
+			v.errorf("Invalid dst range for router %q: %v", r.Name, err)
+		}
+
+		if err := validateRange(r.SrcRange); err != nil {
+			v.errorf("Invalid src range for router %q: %v", r.Name, err)
+		}
+
+		validateLabels(v, r.Labels, fmt.Sprintf("router %q", r.Name))
+	}
+}
+
+func (c *Config) validatePaths(v *validator) {
+	seen := make(map[string]struct{})
+	for i := range c.Paths {
+		p := &c.Paths[i]
+		if p.Name == "" {
+			v.errorf("Path #%d has no name", i)
+			continue
+		}
+
+		if _, ok := seen[p.Name]; ok {
+			v.errorf("Path %q is defined more than once", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+
+		c.validatePath(v, p)
+	}
+}
+
+func (c *Config) validatePath(v *validator, p *Path) {
+	if len(p.Hops) == 0 {
+		v.errorf("Path %q has no hops", p.Name)
+	}
+
+	for j := range p.Hops {
+		if !c.routerExists(p.Hops[j]) {
+			v.errorf("Router %q of path %q does not exist", p.Hops[j], p.Name)
+		}
+	}
+
+	if p.SrcAddr != nil && net.ParseIP(*p.SrcAddr).To4() == nil {
+		v.errorf("Invalid src addr %q of path %q", *p.SrcAddr, p.Name)
+	}
+
+	if p.VRF != nil && *p.VRF == "" {
+		v.errorf("VRF of path %q must not be empty", p.Name)
+	}
+
+	if p.NetNS != nil && (*p.NetNS == "" || strings.ContainsRune(*p.NetNS, '/')) {
+		v.errorf("Invalid network namespace %q of path %q", *p.NetNS, p.Name)
+	}
+
+	for _, cl := range p.Classes {
+		if !c.classExists(cl) {
+			v.errorf("Class %q of path %q does not exist", cl, p.Name)
+		}
+	}
+
+	for j := range p.ResolvedClasses {
+		validateClassSettings(v, p.Name, &p.ResolvedClasses[j])
+	}
+
+	validateLabels(v, p.Labels, fmt.Sprintf("path %q", p.Name))
+}
+
+func validateClassSettings(v *validator, path string, cl *Class) {
+	if cl.PPS != nil && *cl.PPS == 0 {
+		v.errorf("PPS of path %q class %q must be greater than 0", path, cl.Name)
+	}
+
+	if cl.PPS != nil && *cl.PPS > maxPPS {
+		v.errorf("PPS of path %q class %q must not exceed %d", path, cl.Name, maxPPS)
+	}
+
+	if cl.MeasurementLengthMS != nil && *cl.MeasurementLengthMS == 0 {
+		v.errorf("Measurement length of path %q class %q must be greater than 0", path, cl.Name)
+	}
+
+	if cl.TimeoutMS != nil && *cl.TimeoutMS == 0 {
+		v.errorf("Timeout of path %q class %q must be greater than 0", path, cl.Name)
+	}
+
+	if cl.TimeoutMS != nil && cl.MeasurementLengthMS != nil && *cl.TimeoutMS >= transitTimeoutFactor**cl.MeasurementLengthMS {
+		v.errorf("Timeout of path %q class %q must be less than %d times the measurement length", path, cl.Name, transitTimeoutFactor)
+	}
+}
+
+func (c *Config) validatePathGroups(v *validator) {
+	seen := make(map[string]struct{})
+	for i := range c.PathGroups {
+		g := &c.PathGroups[i]
+		if g.Name == "" {
+			v.errorf("Path group #%d has no name", i)
+			continue
+		}
+
+		if _, ok := seen[g.Name]; ok {
+			v.errorf("Path group %q is defined more than once", g.Name)
+		}
+		seen[g.Name] = struct{}{}
+
+		if len(g.Hops) != 0 {
+			v.errorf("Path group %q must not have hops", g.Name)
+		}
+
+		switch g.Type {
+		case pathGroupTypeFullMesh:
+		case pathGroupTypeHubAndSpoke:
+			if len(g.Hubs.Names) == 0 && len(g.Hubs.Tags) == 0 {
+				v.errorf("Path group %q has no hubs", g.Name)
+			}
+
+			if err := g.Hubs.validate(); err != nil {
+				v.errorf("Path group %q: %v", g.Name, err)
+			}
+		case pathGroupTypeVia:
+			if !c.routerExists(g.Via) {
+				v.errorf("Router %q of path group %q does not exist", g.Via, g.Name)
+			}
+		default:
+			v.errorf("Path group %q has unknown type %q", g.Name, g.Type)
+		}
+
+		if err := g.Routers.validate(); err != nil {
+			v.errorf("Path group %q: %v", g.Name, err)
+		}
+	}
+}
+
+func (c *Config) validateDiagnostics(v *validator) {
+	if c.Diagnostics == nil {
+		return
+	}
+
+	if t := c.Diagnostics.LossThresholdPercent; t != nil && (*t <= 0 || *t > 100) {
+		v.errorf("Diagnostics loss threshold must be in range (0, 100]")
+	}
+
+	if d := c.Diagnostics.DurationMS; d != nil && *d == 0 {
+		v.errorf("Diagnostics duration must be greater than 0")
+	}
+}
+
+func (c *Config) validateHealth(v *validator) {
+	h := c.Health
+	if h == nil {
+		return
+	}
+
+	if h.DegradedLossPercent != nil && (*h.DegradedLossPercent <= 0 || *h.DegradedLossPercent > 100) {
+		v.errorf("Health degraded loss threshold must be in range (0, 100]")
+	}
+
+	if h.DownLossPercent != nil && (*h.DownLossPercent <= 0 || *h.DownLossPercent > 100) {
+		v.errorf("Health down loss threshold must be in range (0, 100]")
+	}
+
+	if h.DegradedLossPercent != nil && h.DownLossPercent != nil && *h.DegradedLossPercent > *h.DownLossPercent {
+		v.errorf("Health degraded loss threshold must not exceed the down loss threshold")
+	}
+
+	if h.DegradedRTTP99MS != nil && *h.DegradedRTTP99MS <= 0 {
+		v.errorf("Health degraded RTT p99 threshold must be greater than 0")
+	}
+
+	if h.HysteresisPercent != nil && (*h.HysteresisPercent < 0 || *h.HysteresisPercent >= 100) {
+		v.errorf("Health hysteresis must be in range [0, 100)")
+	}
+
+	if h.ConsecutiveBuckets != nil && *h.ConsecutiveBuckets == 0 {
+		v.errorf("Health consecutive buckets must be greater than 0")
+	}
+}
+
+func (c *Config) validateEvents(v *validator) {
+	e := c.Events
+	if e == nil {
+		return
+	}
+
+	if e.Size != nil && *e.Size == 0 {
+		v.errorf("Events size must be greater than 0")
+	}
+
+	if e.File != nil && *e.File == "" {
+		v.errorf("Events file must not be empty")
+	}
+
+	if e.RTTStepPercent != nil && *e.RTTStepPercent <= 0 {
+		v.errorf("Events RTT step must be greater than 0")
+	}
+
+	if e.RTTStepMinMS != nil && *e.RTTStepMinMS < 0 {
+		v.errorf("Events minimum RTT step must not be negative")
+	}
+}
+
+func (c *Config) classExists(needle string) bool {
+	for i := range c.Classes {
+		if c.Classes[i].Name == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) routerExists(needle string) bool {
+	for i := range c.Routers {
+		if c.Routers[i].Name == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateRange makes sure addrRange is an IPv4 CIDR that GenerateAddrs can handle
+func validateRange(addrRange string) error {
+	ip, n, err := net.ParseCIDR(addrRange)
+	if err != nil {
+		return err
+	}
+
+	if ip.To4() == nil {
+		return fmt.Errorf("%q is not an IPv4 range", addrRange)
+	}
+
+	ones, bits := n.Mask.Size()
+	if bits-ones > maxRangeHostBits {
+		return fmt.Errorf("%q exceeds the maximum of %d addresses", addrRange, 1<<maxRangeHostBits)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+package correlation
+
+import (
+	"sort"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricPrefix = "matroschka_"
+
+	// ProberNode is the name of the prober itself as end of the first and last segment of a path
+	ProberNode = config.ProberNodeName
+)
+
+// StatusProvider provides the probers to correlate
+type StatusProvider interface {
+	Status() []manager.ProberStatus
+}
+
+// HealthProvider provides the health state of probers
+type HealthProvider interface {
+	State(id string) health.State
+}
+
+// Path is a probed path and its health
+type Path struct {
+	ID    string
+	Class string
+	Hops  []string
+	State health.State
+}
+
+// Result are the suspects of a class ordered by descending score
+type Result struct {
+	Class    string    `json:"class"`
+	Failing  []string  `json:"failing"`
+	Routers  []Suspect `json:"routers"`
+	Segments []Suspect `json:"segments"`
+}
+
+// Suspect is a router or a segment between two routers (or the prober) and its suspicion score.
+// The score is 1 if it is part of all failing and no healthy path and 0 if it is not part of any failing path.
+type Suspect struct {
+	Name         string  `json:"name"`
+	From         string  `json:"from,omitempty"`
+	To           string  `json:"to,omitempty"`
+	Score        float64 `json:"score"`
+	FailingPaths int     `json:"failing_paths"`
+	HealthyPaths int     `json:"healthy_paths"`
+}
+
+// PathSuspects returns the router and segment suspects of the result along a path with the given hops
+func (r *Result) PathSuspects(hops []string) (routers []Suspect, segments []Suspect) {
+	onPath := make(map[string]struct{}, 2*len(hops)+1)
+	for _, h := range uniqueRouters(hops) {
+		onPath[h] = struct{}{}
+	}
+
+	for _, s := range pathSegments(hops) {
+		onPath[s[0]+">"+s[1]] = struct{}{}
+	}
+
+	routers = make([]Suspect, 0, len(hops))
+	for _, s := range r.Routers {
+		if _, ok := onPath[s.Name]; ok {
+			routers = append(routers, s)
+		}
+	}
+
+	segments = make([]Suspect, 0, len(hops)+1)
+	for _, s := range r.Segments {
+		if _, ok := onPath[s.From+">"+s.To]; ok {
+			segments = append(segments, s)
+		}
+	}
+
+	return routers, segments
+}
+
+// Correlator finds routers and segments shared by failing paths
+type Correlator struct {
+	status StatusProvider
+	health HealthProvider
+}
+
+// New creates a new correlator
+func New(status StatusProvider, health HealthProvider) *Correlator {
+	return &Correlator{
+		status: status,
+		health: health,
+	}
+}
+
+// Correlate correlates the current health state of all running probers
+func (c *Correlator) Correlate() []Result {
+	status := c.status.Status()
+	paths := make([]Path, 0, len(status))
+	for _, s := range status {
+		if !s.Running || s.Paused {
+			continue
+		}
+
+		hops := make([]string, len(s.Config.Hops))
+		for i := range s.Config.Hops {
+			hops[i] = s.Config.Hops[i].Name
+		}
+
+		paths = append(paths, Path{
+			ID:    s.ID,
+			Class: s.Class,
+			Hops:  hops,
+			State: c.health.State(s.ID),
+		})
+	}
+
+	return Correlate(paths)
+}
+
+// Correlate computes the suspects per class. Paths of unknown state are ignored.
+func Correlate(paths []Path) []Result {
+	byClass := make(map[string][]Path)
+	for _, p := range paths {
+		if p.State == health.StateUnknown {
+			continue
+		}
+
+		byClass[p.Class] = append(byClass[p.Class], p)
+	}
+
+	ret := make([]Result, 0, len(byClass))
+	for class, paths := range byClass {
+		ret = append(ret, correlateClass(class, paths))
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Class < ret[j].Class
+	})
+
+	return ret
+}
+
+func correlateClass(class string, paths []Path) Result {
+	ret := Result{
+		Class:   class,
+		Failing: make([]string, 0),
+	}
+
+	routers := make(map[string]*Suspect)
+	segments := make(map[string]*Suspect)
+	for _, p := range paths {
+		failing := p.State != health.StateUp
+		if failing {
+			ret.Failing = append(ret.Failing, p.ID)
+		}
+
+		for _, r := range uniqueRouters(p.Hops) {
+			count(routers, r, "", "", failing)
+		}
+
+		for _, s := range pathSegments(p.Hops) {
+			count(segments, s[0]+">"+s[1], s[0], s[1], failing)
+		}
+	}
+	sort.Strings(ret.Failing)
+
+	ret.Routers = scored(routers, len(ret.Failing))
+	ret.Segments = scored(segments, len(ret.Failing))
+	return ret
+}
+
+func count(suspects map[string]*Suspect, name string, from string, to string, failing bool) {
+	s := suspects[name]
+	if s == nil {
+		s = &Suspect{
+			Name: name,
+			From: from,
+			To:   to,
+		}
+		suspects[name] = s
+	}
+
+	if failing {
+		s.FailingPaths++
+	} else {
+		s.HealthyPaths++
+	}
+}
+
+// scored returns the suspects with their scores ordered by descending score
+func scored(suspects map[string]*Suspect, failing int) []Suspect {
+	ret := make([]Suspect, 0, len(suspects))
+	for _, s := range suspects {
+		if s.FailingPaths > 0 {
+			f := float64(s.FailingPaths)
+			s.Score = f / float64(failing) * f / float64(s.FailingPaths+s.HealthyPaths)
+		}
+
+		ret = append(ret, *s)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Score != ret[j].Score {
+			return ret[i].Score > ret[j].Score
+		}
+
+		return ret[i].Name < ret[j].Name
+	})
+
+	return ret
+}
+
+// uniqueRouters returns the routers of a path counting routers traversed more than once only once
+func uniqueRouters(hops []string) []string {
+	seen := make(map[string]struct{}, len(hops))
+	ret := make([]string, 0, len(hops))
+	for _, h := range hops {
+		if _, ok := seen[h]; ok {
+			continue
+		}
+
+		seen[h] = struct{}{}
+		ret = append(ret, h)
+	}
+
+	return ret
+}
+
+// pathSegments returns the unique segments of a path from the prober along all hops back to the prober
+func pathSegments(hops []string) [][2]string {
+	nodes := append(append([]string{ProberNode}, hops...), ProberNode)
+	seen := make(map[[2]string]struct{}, len(nodes))
+	ret := make([][2]string, 0, len(nodes)-1)
+	for i := 1; i < len(nodes); i++ {
+		s := [2]string{nodes[i-1], nodes[i]}
+		if _, ok := seen[s]; ok {
+			continue
+		}
+
+		seen[s] = struct{}{}
+		ret = append(ret, s)
+	}
+
+	return ret
+}
+
+// Describe is required by prometheus interface
+func (c *Correlator) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect collects the suspicion scores and sends them to prometheus
+func (c *Correlator) Collect(ch chan<- prometheus.Metric) {
+	routerDesc := prometheus.NewDesc(metricPrefix+"router_suspicion_score", "Likelihood of a router causing the failing paths of a class", []string{"tos", "router"}, nil)
+	segmentDesc := prometheus.NewDesc(metricPrefix+"segment_suspicion_score", "Likelihood of a segment causing the failing paths of a class", []string{"tos", "from", "to"}, nil)
+
+	for _, r := range c.Correlate() {
+		for _, s := range r.Routers {
+			ch <- prometheus.MustNewConstMetric(routerDesc, prometheus.GaugeValue, s.Score, r.Class, s.Name)
+		}
+
+		for _, s := range r.Segments {
+			ch <- prometheus.MustNewConstMetric(segmentDesc, prometheus.GaugeValue, s.Score, r.Class, s.From, s.To)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package correlation
+
+import (
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorrelate(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    []Path
+		expected []Result
+	}{
+		{
+			name: "Test #1: router shared by all failing paths",
+			paths: []Path{
+				{ID: "a-x-b@BE", Class: "BE", Hops: []string{"a", "x", "b"}, State: health.StateDown},
+				{ID: "c-x-d@BE", Class: "BE", Hops: []string{"c", "x", "d"}, State: health.StateDegraded},
+				{ID: "a-b@BE", Class: "BE", Hops: []string{"a", "b"}, State: health.StateUp},
+				{ID: "a-x@EF", Class: "EF", Hops: []string{"a", "x"}, State: health.StateUnknown},
+			},
+			expected: []Result{
+				{
+					Class:   "BE",
+					Failing: []string{"a-x-b@BE", "c-x-d@BE"},
+					Routers: []Suspect{
+						{Name: "x", Score: 1, FailingPaths: 2},
+						{Name: "c", Score: 0.5, FailingPaths: 1},
+						{Name: "d", Score: 0.5, FailingPaths: 1},
+						{Name: "a", Score: 0.25, FailingPaths: 1, HealthyPaths: 1},
+						{Name: "b", Score: 0.25, FailingPaths: 1, HealthyPaths: 1},
+					},
+					Segments: []Suspect{
+						{Name: "a>x", From: "a", To: "x", Score: 0.5, FailingPaths: 1},
+						{Name: "c>x", From: "c", To: "x", Score: 0.5, FailingPaths: 1},
+						{Name: "d>prober", From: "d", To: "prober", Score: 0.5, FailingPaths: 1},
+						{Name: "prober>c", From: "prober", To: "c", Score: 0.5, FailingPaths: 1},
+						{Name: "x>b", From: "x", To: "b", Score: 0.5, FailingPaths: 1},
+						{Name: "x>d", From: "x", To: "d", Score: 0.5, FailingPaths: 1},
+						{Name: "b>prober", From: "b", To: "prober", Score: 0.25, FailingPaths: 1, HealthyPaths: 1},
+						{Name: "prober>a", From: "prober", To: "a", Score: 0.25, FailingPaths: 1, HealthyPaths: 1},
+						{Name: "a>b", From: "a", To: "b", HealthyPaths: 1},
+					},
+				},
+			},
+		},
+		{
+			name: "Test #2: no failing paths and routers traversed twice",
+			paths: []Path{
+				{ID: "a-b-a@BE", Class: "BE", Hops: []string{"a", "b", "a"}, State: health.StateUp},
+			},
+			expected: []Result{
+				{
+					Class:   "BE",
+					Failing: []string{},
+					Routers: []Suspect{
+						{Name: "a", HealthyPaths: 1},
+						{Name: "b", HealthyPaths: 1},
+					},
+					Segments: []Suspect{
+						{Name: "a>b", From: "a", To: "b", HealthyPaths: 1},
+						{Name: "a>prober", From: "a", To: "prober", HealthyPaths: 1},
+						{Name: "b>a", From: "b", To: "a", HealthyPaths: 1},
+						{Name: "prober>a", From: "prober", To: "a", HealthyPaths: 1},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Correlate(test.paths), test.name)
+	}
+}
+
+func TestPathSuspects(t *testing.T) {
+	res := Correlate([]Path{
+		{ID: "a-x@BE", Class: "BE", Hops: []string{"a", "x"}, State: health.StateDown},
+		{ID: "b@BE", Class: "BE", Hops: []string{"b"}, State: health.StateUp},
+	})[0]
+
+	routers, segments := res.PathSuspects([]string{"a", "x"})
+	assert.Equal(t, []Suspect{
+		{Name: "a", Score: 1, FailingPaths: 1},
+		{Name: "x", Score: 1, FailingPaths: 1},
+	}, routers)
+	assert.Equal(t, []Suspect{
+		{Name: "a>x", From: "a", To: "x", Score: 1, FailingPaths: 1},
+		{Name: "prober>a", From: "prober", To: "a", Score: 1, FailingPaths: 1},
+		{Name: "x>prober", From: "x", To: "prober", Score: 1, FailingPaths: 1},
+	}, segments)
+
+	routers, segments = res.PathSuspects([]string{"b"})
+	assert.Equal(t, []Suspect{{Name: "b", HealthyPaths: 1}}, routers)
+	assert.Equal(t, []Suspect{
+		{Name: "b>prober", From: "b", To: "prober", HealthyPaths: 1},
+		{Name: "prober>b", From: "prober", To: "b", HealthyPaths: 1},
+	}, segments)
+}
@@ -0,0 +1,300 @@
+package diagnostics
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	metricPrefix = "matroschka_diagnostics_"
+)
+
+// Config is the configuration of the diagnostics
+type Config struct {
+	LossThresholdPercent float64
+	Duration             time.Duration
+	Cooldown             time.Duration
+}
+
+// Diagnostics starts temporary sub-path probers for paths that exceed the loss threshold
+// in order to find out which part of the path is responsible for the loss
+type Diagnostics struct {
+	ctx    context.Context
+	cfg    Config
+	runs   map[string]*run // Key is the ID of the prober of the path
+	closed bool
+	l      sync.Mutex
+}
+
+type run struct {
+	path     string
+	tos      string
+	active   bool
+	count    uint64
+	finished time.Time
+	timer    *time.Timer
+	subPaths []*subPath
+	verdict  *subPath
+}
+
+type subPath struct {
+	name     string
+	hops     []prober.Hop
+	prober   *prober.Prober
+	sent     uint64
+	received uint64
+}
+
+// New creates a new diagnostics instance. Sub-path probers stop when ctx is cancelled.
+func New(ctx context.Context, cfg Config) *Diagnostics {
+	return &Diagnostics{
+		ctx:  ctx,
+		cfg:  cfg,
+		runs: make(map[string]*run),
+	}
+}
+
+// Observe checks a finished measurement of a prober and triggers a diagnostics run if required
+func (d *Diagnostics) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	if lossPercent(m.Sent, m.Received) < d.cfg.LossThresholdPercent {
+		return
+	}
+
+	d.trigger(p)
+}
+
+func (d *Diagnostics) trigger(p *prober.Prober) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	if d.closed {
+		return
+	}
+
+	r := d.runs[p.ID()]
+	if r == nil {
+		r = &run{
+			path: p.Path(),
+			tos:  p.TOS().Name,
+		}
+		d.runs[p.ID()] = r
+	}
+
+	if r.active || time.Since(r.finished) < d.cfg.Cooldown {
+		return
+	}
+
+	subPaths := generateSubPaths(p.Config().Hops)
+	if len(subPaths) == 0 {
+		return
+	}
+
+	log.Infof("Loss on path %q class %q exceeded %.2f%%. Starting diagnostics of %d sub-paths", r.path, r.tos, d.cfg.LossThresholdPercent, len(subPaths))
+	r.active = true
+	r.count++
+	r.subPaths = subPaths
+	r.verdict = nil
+
+	for _, sp := range subPaths {
+		cfg := p.Config()
+		cfg.Hops = sp.hops
+		pr, err := prober.New(cfg)
+		if err != nil {
+			log.Errorf("Unable to get prober for sub-path %q: %v", sp.name, err)
+			continue
+		}
+
+		pr.AddObserver(sp)
+		err = pr.Start(d.ctx)
+		if err != nil {
+			log.Errorf("Unable to start prober for sub-path %q: %v", sp.name, err)
+			continue
+		}
+
+		sp.prober = pr
+	}
+
+	r.timer = time.AfterFunc(d.cfg.Duration, func() {
+		d.finish(r)
+	})
+}
+
+// Forget stops a running diagnostics of a removed prober and drops its results
+func (d *Diagnostics) Forget(id string) {
+	d.l.Lock()
+	r := d.runs[id]
+	delete(d.runs, id)
+	active := r != nil && r.active
+	if active {
+		r.active = false
+		r.timer.Stop()
+	}
+	d.l.Unlock()
+
+	if active {
+		r.stopProbers()
+	}
+}
+
+// Close stops all running diagnostics and waits for their sub-path probers. No runs are started afterwards.
+func (d *Diagnostics) Close() {
+	d.l.Lock()
+	d.closed = true
+	active := make([]*run, 0)
+	for _, r := range d.runs {
+		if r.active {
+			r.active = false
+			r.timer.Stop()
+			active = append(active, r)
+		}
+	}
+	d.l.Unlock()
+
+	for _, r := range active {
+		r.stopProbers()
+	}
+}
+
+func (d *Diagnostics) finish(r *run) {
+	d.l.Lock()
+	if !r.active {
+		// Forgotten or closed in the meantime
+		d.l.Unlock()
+		return
+	}
+	d.l.Unlock()
+
+	r.stopProbers()
+
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	r.active = false
+	r.finished = time.Now()
+	r.verdict = verdict(r.subPaths, d.cfg.LossThresholdPercent)
+
+	for _, sp := range r.subPaths {
+		log.Infof("Diagnostics of path %q class %q: sub-path %q lost %.2f%% of %d probes", r.path, r.tos, sp.name, sp.lossPercent(), atomic.LoadUint64(&sp.sent))
+	}
+
+	if r.verdict == nil {
+		log.Infof("Diagnostics of path %q class %q: loss was not reproduced on any sub-path", r.path, r.tos)
+		return
+	}
+
+	log.Infof("Diagnostics of path %q class %q: loss reproduced on sub-path %q", r.path, r.tos, r.verdict.name)
+}
+
+func (r *run) stopProbers() {
+	for _, sp := range r.subPaths {
+		if sp.prober != nil {
+			sp.prober.Stop()
+		}
+	}
+}
+
+// Observe accumulates the finished measurements of a sub-path prober
+func (sp *subPath) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	atomic.AddUint64(&sp.sent, m.Sent)
+	atomic.AddUint64(&sp.received, m.Received)
+}
+
+func (sp *subPath) lossPercent() float64 {
+	return lossPercent(atomic.LoadUint64(&sp.sent), atomic.LoadUint64(&sp.received))
+}
+
+// generateSubPaths returns all proper prefixes of a path followed by single hop loops to
+// all hops that are not already covered by the prefixes
+func generateSubPaths(hops []prober.Hop) []*subPath {
+	ret := make([]*subPath, 0)
+	for i := 1; i < len(hops); i++ {
+		ret = append(ret, newSubPath(hops[:i]))
+	}
+
+	for i := 1; i < len(hops); i++ {
+		ret = append(ret, newSubPath(hops[i:i+1]))
+	}
+
+	return ret
+}
+
+func newSubPath(hops []prober.Hop) *subPath {
+	names := make([]string, len(hops))
+	for i := range hops {
+		names[i] = hops[i].Name
+	}
+
+	return &subPath{
+		name: strings.Join(names, "-"),
+		hops: append([]prober.Hop{}, hops...),
+	}
+}
+
+// verdict returns the first sub-path reproducing the loss. As prefixes come first ordered
+// by length this is the shortest lossy prefix or, if all prefixes are fine, the first lossy single hop loop.
+func verdict(subPaths []*subPath, thresholdPercent float64) *subPath {
+	for _, sp := range subPaths {
+		if atomic.LoadUint64(&sp.sent) == 0 {
+			continue
+		}
+
+		if sp.lossPercent() >= thresholdPercent {
+			return sp
+		}
+	}
+
+	return nil
+}
+
+func lossPercent(sent uint64, received uint64) float64 {
+	if sent == 0 || received >= sent {
+		return 0
+	}
+
+	return float64(sent-received) / float64(sent) * 100
+}
+
+// Describe is required by prometheus interface
+func (d *Diagnostics) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect collects the diagnostics results and sends them to prometheus
+func (d *Diagnostics) Collect(ch chan<- prometheus.Metric) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	activeDesc := prometheus.NewDesc(metricPrefix+"active", "Diagnostics run in progress", []string{"tos", "path"}, nil)
+	runsDesc := prometheus.NewDesc(metricPrefix+"runs_total", "Diagnostics runs triggered by loss", []string{"tos", "path"}, nil)
+	lossDesc := prometheus.NewDesc(metricPrefix+"subpath_loss_ratio", "Loss ratio of a sub-path during the last diagnostics run", []string{"tos", "path", "subpath"}, nil)
+	verdictDesc := prometheus.NewDesc(metricPrefix+"verdict", "Sub-path that reproduced the loss during the last diagnostics run", []string{"tos", "path", "subpath"}, nil)
+
+	for _, r := range d.runs {
+		active := float64(0)
+		if r.active {
+			active = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(activeDesc, prometheus.GaugeValue, active, r.tos, r.path)
+		ch <- prometheus.MustNewConstMetric(runsDesc, prometheus.CounterValue, float64(r.count), r.tos, r.path)
+
+		if r.active || r.finished.IsZero() {
+			continue
+		}
+
+		for _, sp := range r.subPaths {
+			ch <- prometheus.MustNewConstMetric(lossDesc, prometheus.GaugeValue, sp.lossPercent()/100, r.tos, r.path, sp.name)
+		}
+
+		if r.verdict != nil {
+			ch <- prometheus.MustNewConstMetric(verdictDesc, prometheus.GaugeValue, 1, r.tos, r.path, r.verdict.name)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package diagnostics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSubPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		hops     []string
+		expected []string
+	}{
+		{
+			name:     "Test #1: single hop path",
+			hops:     []string{"A"},
+			expected: []string{},
+		},
+		{
+			name:     "Test #2: three hop path",
+			hops:     []string{"A", "B", "C"},
+			expected: []string{"A", "A-B", "B", "C"},
+		},
+	}
+
+	for _, test := range tests {
+		hops := make([]prober.Hop, len(test.hops))
+		for i := range test.hops {
+			hops[i] = prober.Hop{Name: test.hops[i]}
+		}
+
+		names := make([]string, 0)
+		for _, sp := range generateSubPaths(hops) {
+			names = append(names, sp.name)
+		}
+
+		assert.Equal(t, test.expected, names, test.name)
+	}
+}
+
+func TestVerdict(t *testing.T) {
+	tests := []struct {
+		name     string
+		subPaths []*subPath
+		expected string
+	}{
+		{
+			name: "Test #1: loss not reproduced",
+			subPaths: []*subPath{
+				{name: "A", sent: 100, received: 100},
+				{name: "B", sent: 100, received: 99},
+			},
+			expected: "",
+		},
+		{
+			name: "Test #2: shortest lossy prefix wins",
+			subPaths: []*subPath{
+				{name: "A", sent: 100, received: 100},
+				{name: "A-B", sent: 100, received: 50},
+				{name: "A-B-C", sent: 100, received: 50},
+				{name: "B", sent: 100, received: 50},
+			},
+			expected: "A-B",
+		},
+		{
+			name: "Test #3: sub-paths without probes are ignored",
+			subPaths: []*subPath{
+				{name: "A"},
+				{name: "B", sent: 100, received: 10},
+			},
+			expected: "B",
+		},
+	}
+
+	for _, test := range tests {
+		v := verdict(test.subPaths, 5)
+		name := ""
+		if v != nil {
+			name = v.name
+		}
+
+		assert.Equal(t, test.expected, name, test.name)
+	}
+}
+
+func TestForget(t *testing.T) {
+	d := New(context.Background(), Config{})
+	r := &run{
+		path:     "A-B",
+		tos:      "BE",
+		active:   true,
+		subPaths: []*subPath{newSubPath([]prober.Hop{{Name: "A"}})},
+	}
+	r.timer = time.AfterFunc(time.Hour, func() {
+		d.finish(r)
+	})
+	d.runs["p1@BE"] = r
+	d.runs["p2@BE"] = &run{path: "A-C", tos: "BE"}
+
+	d.Forget("p1@BE")
+	d.Forget("unknown@BE")
+
+	assert.False(t, r.active)
+	assert.False(t, r.timer.Stop(), "timer must be stopped")
+	assert.Len(t, d.runs, 1)
+	assert.Contains(t, d.runs, "p2@BE")
+
+	// A finish racing with Forget must not touch the forgotten run
+	d.finish(r)
+	assert.True(t, r.finished.IsZero())
+}
+
+func TestClose(t *testing.T) {
+	d := New(context.Background(), Config{})
+	r := &run{
+		path:     "A-B",
+		tos:      "BE",
+		active:   true,
+		subPaths: []*subPath{newSubPath([]prober.Hop{{Name: "A"}})},
+	}
+	r.timer = time.AfterFunc(time.Hour, func() {
+		d.finish(r)
+	})
+	d.runs["p1@BE"] = r
+
+	d.Close()
+	assert.False(t, r.active)
+	assert.False(t, r.timer.Stop(), "timer must be stopped")
+
+	p, err := prober.New(prober.Config{
+		PathName: "A-C",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		Hops: []prober.Hop{
+			{Name: "A"},
+			{Name: "C"},
+		},
+	})
+	assert.NoError(t, err)
+
+	d.trigger(p)
+	assert.NotContains(t, d.runs, p.ID(), "no runs must be started after Close")
+}
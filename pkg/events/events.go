@@ -0,0 +1,356 @@
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	log "github.com/sirupsen/logrus"
+)
+
+// Event types
+const (
+	TypeStateChange = "state_change"
+	TypeLossOnset   = "loss_onset"
+	TypeLossEnd     = "loss_end"
+	TypeRTTStep     = "rtt_step"
+	TypeTOSRemarked = "tos_remarked"
+	TypeReload      = "reload"
+)
+
+const (
+	// rttStepBuckets is the number of consecutive buckets an RTT change must persist to be a step
+	rttStepBuckets = 3
+
+	// rttBaselineWeight is the weight of a new bucket in the moving RTT baseline
+	rttBaselineWeight = 0.1
+)
+
+// Event is a notable event of a prober or the prober process
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Prober  string    `json:"prober,omitempty"`
+	Path    string    `json:"path,omitempty"`
+	Class   string    `json:"class,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Config is the configuration of the event log
+type Config struct {
+	Size         int    // Maximum number of events kept
+	File         string // Events are appended to this file and loaded from it on startup if set. It holds at most 2*Size events.
+	RTTStepRatio float64
+	RTTStepMin   time.Duration
+}
+
+// Log keeps a bounded history of events
+type Log struct {
+	cfg     Config
+	events  []Event // Ring buffer
+	next    int
+	full    bool
+	probers map[string]*proberState
+	file    *os.File
+	l       sync.Mutex
+}
+
+type proberState struct {
+	lossy         bool
+	rttBaseline   float64
+	rttDeviations int
+	tosMismatches uint64
+}
+
+// New creates a new event log. If a file is configured, its most recent events are loaded.
+func New(cfg Config) (*Log, error) {
+	l := &Log{
+		cfg:     cfg,
+		events:  make([]Event, cfg.Size),
+		probers: make(map[string]*proberState),
+	}
+
+	if cfg.File == "" {
+		return l, nil
+	}
+
+	err := l.load()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to load events: %v", err)
+	}
+
+	return l, nil
+}
+
+// load reads the events from the file, keeps the most recent ones and rewrites the file with them
+func (l *Log) load() error {
+	f, err := os.Open(l.cfg.File)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err == nil {
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			e := Event{}
+			if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+				log.Warnf("Skipping invalid event in %q: %v", l.cfg.File, err)
+				continue
+			}
+
+			l.append(e)
+		}
+		f.Close()
+
+		if err := s.Err(); err != nil {
+			return err
+		}
+	}
+
+	return l.compact()
+}
+
+// compact replaces the file with the events in memory and reopens it for appending
+func (l *Log) compact() error {
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+
+	err := l.rewrite()
+
+	// Keep appending even if rewriting failed
+	f, oerr := os.OpenFile(l.cfg.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if oerr == nil {
+		l.file = f
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return oerr
+}
+
+// rewrite replaces the file with the events in memory
+func (l *Log) rewrite() error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.cfg.File), filepath.Base(l.cfg.File)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	for _, e := range l.all() {
+		if err := enc.Encode(e); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), l.cfg.File)
+}
+
+// Close closes the event file
+func (l *Log) Close() error {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	return l.file.Close()
+}
+
+// Add adds an event
+func (l *Log) Add(e Event) {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	l.add(e)
+}
+
+func (l *Log) add(e Event) {
+	log.Debugf("Event %s %s: %s", e.Type, e.Prober, e.Message)
+	wrapped := l.append(e)
+
+	if l.file == nil {
+		return
+	}
+
+	err := json.NewEncoder(l.file).Encode(e)
+	if err != nil {
+		log.Errorf("Unable to write event to %q: %v", l.cfg.File, err)
+	}
+
+	// The file only needs the events in memory. Compacting it whenever the ring wraps
+	// bounds it to twice the size of the ring.
+	if wrapped {
+		err = l.compact()
+		if err != nil {
+			log.Errorf("Unable to compact %q: %v", l.cfg.File, err)
+		}
+	}
+}
+
+// append adds an event to the ring and returns whether the ring wrapped around
+func (l *Log) append(e Event) bool {
+	if len(l.events) == 0 {
+		return false
+	}
+
+	l.events[l.next] = e
+	l.next = (l.next + 1) % len(l.events)
+	if l.next == 0 {
+		l.full = true
+		return true
+	}
+
+	return false
+}
+
+// all returns all events, oldest first
+func (l *Log) all() []Event {
+	if !l.full {
+		return append([]Event{}, l.events[:l.next]...)
+	}
+
+	return append(append([]Event{}, l.events[l.next:]...), l.events[:l.next]...)
+}
+
+// Query returns all events of a path and class that happened at or after since, oldest first.
+// Empty path or class match all events.
+func (l *Log) Query(path string, class string, since time.Time) []Event {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	ret := make([]Event, 0)
+	for _, e := range l.all() {
+		if path != "" && e.Path != path {
+			continue
+		}
+
+		if class != "" && e.Class != class {
+			continue
+		}
+
+		if e.Time.Before(since) {
+			continue
+		}
+
+		ret = append(ret, e)
+	}
+
+	return ret
+}
+
+// StateChanged records health state transitions
+func (l *Log) StateChanged(t health.Transition) {
+	l.Add(newProberEvent(t.Prober, t.Ts, TypeStateChange, fmt.Sprintf("State changed from %s to %s", t.From, t.To)))
+}
+
+// Forget drops the state of a removed prober
+func (l *Log) Forget(id string) {
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	delete(l.probers, id)
+}
+
+// Observe detects loss onset and end, RTT steps and remarked TOS in finished measurements
+func (l *Log) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	if m.Sent == 0 {
+		return
+	}
+
+	l.l.Lock()
+	defer l.l.Unlock()
+
+	s := l.probers[p.ID()]
+	if s == nil {
+		s = &proberState{}
+		l.probers[p.ID()] = s
+	}
+
+	lossy := m.Received < m.Sent
+	if lossy && !s.lossy {
+		l.add(newProberEvent(p, ts, TypeLossOnset, fmt.Sprintf("Loss started: %d of %d probes lost", m.Sent-m.Received, m.Sent)))
+	}
+
+	if !lossy && s.lossy {
+		l.add(newProberEvent(p, ts, TypeLossEnd, "Loss ended"))
+	}
+	s.lossy = lossy
+
+	if m.Received > 0 {
+		l.checkRTT(p, ts, s, float64(m.RTTAvg()))
+	}
+
+	mismatches, tos := p.TOSMismatches()
+	if mismatches > s.tosMismatches {
+		l.add(newProberEvent(p, ts, TypeTOSRemarked, fmt.Sprintf("%d probes returned with TOS other than 0x%02x, last seen 0x%02x", mismatches-s.tosMismatches, p.TOS().Value, tos)))
+	}
+	s.tosMismatches = mismatches
+}
+
+// checkRTT records a step if the average RTT deviates from the moving baseline for several buckets
+func (l *Log) checkRTT(p *prober.Prober, ts int64, s *proberState, rtt float64) {
+	if s.rttBaseline == 0 {
+		s.rttBaseline = rtt
+		return
+	}
+
+	threshold := s.rttBaseline * l.cfg.RTTStepRatio
+	if threshold < float64(l.cfg.RTTStepMin) {
+		threshold = float64(l.cfg.RTTStepMin)
+	}
+
+	diff := rtt - s.rttBaseline
+	if diff < 0 {
+		diff = -diff
+	}
+
+	if diff < threshold {
+		s.rttDeviations = 0
+		s.rttBaseline += (rtt - s.rttBaseline) * rttBaselineWeight
+		return
+	}
+
+	s.rttDeviations++
+	if s.rttDeviations < rttStepBuckets {
+		return
+	}
+
+	l.add(newProberEvent(p, ts, TypeRTTStep, fmt.Sprintf("Average RTT changed from %s to %s", time.Duration(s.rttBaseline), time.Duration(rtt))))
+	s.rttBaseline = rtt
+	s.rttDeviations = 0
+}
+
+func newProberEvent(p *prober.Prober, ts int64, typ string, msg string) Event {
+	return Event{
+		Time:    time.Unix(0, ts).UTC(),
+		Type:    typ,
+		Prober:  p.ID(),
+		Path:    p.PathName(),
+		Class:   p.TOS().Name,
+		Message: msg,
+	}
+}
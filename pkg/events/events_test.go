@@ -0,0 +1,151 @@
+package events
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+func testProber(t *testing.T) *prober.Prober {
+	p, err := prober.New(prober.Config{
+		PathName: "a-b",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+	})
+	assert.NoError(t, err)
+
+	return p
+}
+
+func eventTypes(events []Event) []string {
+	ret := make([]string, len(events))
+	for i := range events {
+		ret[i] = events[i].Type
+	}
+
+	return ret
+}
+
+func TestQuery(t *testing.T) {
+	l, err := New(Config{
+		Size: 3,
+	})
+	assert.NoError(t, err)
+
+	for i, path := range []string{"a", "b", "a", "a"} {
+		l.Add(Event{
+			Time:    time.Unix(int64(i), 0),
+			Type:    TypeLossOnset,
+			Path:    path,
+			Message: "test",
+		})
+	}
+
+	assert.Len(t, l.Query("", "", time.Time{}), 3, "Oldest event is dropped")
+	assert.Len(t, l.Query("a", "", time.Time{}), 2)
+	assert.Len(t, l.Query("a", "", time.Unix(3, 0)), 1)
+	assert.Len(t, l.Query("", "EF", time.Time{}), 0)
+}
+
+func TestObserve(t *testing.T) {
+	l, err := New(Config{
+		Size:         100,
+		RTTStepRatio: 0.5,
+		RTTStepMin:   time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	p := testProber(t)
+	ms := uint64(time.Millisecond)
+	buckets := []*measurement.Measurement{
+		{Sent: 10, Received: 10, RTTSum: 100 * ms},
+		{Sent: 10, Received: 8, RTTSum: 80 * ms},
+		{Sent: 10, Received: 10, RTTSum: 100 * ms},
+		{Sent: 10, Received: 10, RTTSum: 300 * ms},
+		{Sent: 10, Received: 10, RTTSum: 100 * ms},
+		{Sent: 10, Received: 10, RTTSum: 300 * ms},
+		{Sent: 10, Received: 10, RTTSum: 300 * ms},
+		{Sent: 10, Received: 10, RTTSum: 300 * ms},
+		{Sent: 10, Received: 10, RTTSum: 300 * ms},
+	}
+
+	for i, m := range buckets {
+		l.Observe(p, int64(i)*int64(time.Second), m)
+	}
+
+	events := l.Query("", "", time.Time{})
+	assert.Equal(t, []string{TypeLossOnset, TypeLossEnd, TypeRTTStep}, eventTypes(events))
+	assert.Equal(t, time.Unix(7, 0).UTC(), events[2].Time)
+	assert.Equal(t, "a-b@BE", events[2].Prober)
+}
+
+func TestPersistence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+	l, err := New(Config{
+		Size: 2,
+		File: file,
+	})
+	assert.NoError(t, err)
+
+	l.StateChanged(health.Transition{
+		Prober: testProber(t),
+		Ts:     int64(time.Second),
+		From:   health.StateUp,
+		To:     health.StateDown,
+	})
+	l.Add(Event{Time: time.Unix(2, 0).UTC(), Type: TypeReload, Message: "Config reloaded"})
+	l.Add(Event{Time: time.Unix(3, 0).UTC(), Type: TypeReload, Message: "Config reloaded"})
+	assert.NoError(t, l.Close())
+
+	l, err = New(Config{
+		Size: 2,
+		File: file,
+	})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	assert.Equal(t, []Event{
+		{Time: time.Unix(2, 0).UTC(), Type: TypeReload, Message: "Config reloaded"},
+		{Time: time.Unix(3, 0).UTC(), Type: TypeReload, Message: "Config reloaded"},
+	}, l.Query("", "", time.Time{}))
+}
+
+func TestCompaction(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "events.jsonl")
+	l, err := New(Config{
+		Size: 3,
+		File: file,
+	})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	for i := 0; i < 100; i++ {
+		l.Add(Event{Time: time.Unix(int64(i), 0).UTC(), Type: TypeReload, Message: "Config reloaded"})
+
+		b, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, bytes.Count(b, []byte("\n")), 6, "file must not hold more than twice the events in memory")
+	}
+
+	l.Add(Event{Time: time.Unix(100, 0).UTC(), Type: TypeReload, Message: "Config reloaded"})
+	assert.NoError(t, l.Close())
+
+	l, err = New(Config{
+		Size: 3,
+		File: file,
+	})
+	assert.NoError(t, err)
+
+	events := l.Query("", "", time.Time{})
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, time.Unix(100, 0).UTC(), events[2].Time)
+	}
+}
@@ -0,0 +1,240 @@
+package frontend
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	apiProbersPath = "/api/v1/probers"
+)
+
+// ProberManager is the interface to change probers at runtime
+type ProberManager interface {
+	AddPath(p config.Path) ([]string, error)
+	Remove(id string) error
+	Pause(id string) error
+	Resume(id string) error
+}
+
+type addPathRequest struct {
+	Name                string            `json:"name"`
+	Hops                []string          `json:"hops"`
+	Classes             []string          `json:"classes"`
+	MeasurementLengthMS *uint64           `json:"measurement_length_ms"`
+	PayloadSizeBytes    *uint64           `json:"payload_size_bytes"`
+	PPS                 *uint64           `json:"pps"`
+	TimeoutMS           *uint64           `json:"timeout"`
+	Labels              map[string]string `json:"labels"`
+}
+
+type addPathResponse struct {
+	IDs []string `json:"ids"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func (fe *Frontend) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc(apiProbersPath, fe.handleProbersRequest)
+	mux.HandleFunc(apiProbersPath+"/", fe.handleProberRequest)
+}
+
+func (fe *Frontend) manager() ProberManager {
+	m, ok := fe.proberReg.(ProberManager)
+	if !ok {
+		return nil
+	}
+
+	return m
+}
+
+// handleProbersRequest handles requests to the collection of probers
+func (fe *Frontend) handleProbersRequest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		fe.handleListProbers(w, r)
+	case http.MethodPost:
+		fe.handleAddPath(w, r)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleProberRequest handles requests to /api/v1/probers/{id}[/{action}]
+func (fe *Frontend) handleProberRequest(w http.ResponseWriter, r *http.Request) {
+	id, action, err := parseProberPath(r.URL)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch {
+	case action == "measurements" && r.Method == http.MethodGet:
+		fe.handleMeasurements(w, r, id)
+	case action == "" && r.Method == http.MethodDelete:
+		fe.handleManagerCall(w, r, id, "remove")
+	case action == "pause" && r.Method == http.MethodPost:
+		fe.handleManagerCall(w, r, id, action)
+	case action == "resume" && r.Method == http.MethodPost:
+		fe.handleManagerCall(w, r, id, action)
+	case action == "recording" && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+		fe.handleRecording(w, r, id)
+	case action == "capture" && (r.Method == http.MethodPost || r.Method == http.MethodDelete):
+		fe.handleCapture(w, r, id)
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// parseProberPath extracts the prober ID and the optional action from the request URL.
+// IDs containing a slash must be escaped by the client.
+func parseProberPath(u *url.URL) (string, string, error) {
+	parts := strings.Split(strings.TrimPrefix(u.EscapedPath(), apiProbersPath+"/"), "/")
+	if len(parts) > 2 || parts[0] == "" {
+		return "", "", errors.New("Invalid prober path")
+	}
+
+	id, err := url.PathUnescape(parts[0])
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(parts) == 1 {
+		return id, "", nil
+	}
+
+	return id, parts[1], nil
+}
+
+func (fe *Frontend) handleAddPath(w http.ResponseWriter, r *http.Request) {
+	m := fe.authorizedManager(w, r)
+	if m == nil {
+		return
+	}
+
+	req := addPathRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Unable to decode request: "+err.Error())
+		return
+	}
+
+	ids, err := m.AddPath(config.Path{
+		Name:                req.Name,
+		Hops:                req.Hops,
+		Classes:             req.Classes,
+		MeasurementLengthMS: req.MeasurementLengthMS,
+		PayloadSizeBytes:    req.PayloadSizeBytes,
+		PPS:                 req.PPS,
+		TimeoutMS:           req.TimeoutMS,
+		Labels:              req.Labels,
+	})
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	log.Infof("Path %q added via API by %s", req.Name, r.RemoteAddr)
+	writeJSON(w, http.StatusCreated, addPathResponse{
+		IDs: ids,
+	})
+}
+
+func (fe *Frontend) handleManagerCall(w http.ResponseWriter, r *http.Request, id string, action string) {
+	m := fe.authorizedManager(w, r)
+	if m == nil {
+		return
+	}
+
+	var err error
+	switch action {
+	case "remove":
+		err = m.Remove(id)
+	case "pause":
+		err = m.Pause(id)
+	case "resume":
+		err = m.Resume(id)
+	}
+
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	log.Infof("Prober %q: %s requested via API by %s", id, action, r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// authorizedManager returns the prober manager if the request is authorized to change probers.
+// Otherwise an error is written and nil is returned.
+func (fe *Frontend) authorizedManager(w http.ResponseWriter, r *http.Request) ProberManager {
+	if !fe.authorize(w, r) {
+		return nil
+	}
+
+	m := fe.manager()
+	if m == nil {
+		writeError(w, http.StatusNotImplemented, "Changing probers is not supported")
+		return nil
+	}
+
+	return m
+}
+
+// authorize checks the bearer token of a request. Changes are refused if no token is configured.
+func (fe *Frontend) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if fe.cfg.APIToken == "" {
+		writeError(w, http.StatusForbidden, "API is disabled as no token is configured")
+		return false
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(fe.cfg.APIToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+
+	return true
+}
+
+func writeManagerError(w http.ResponseWriter, err error) {
+	var verr *config.ValidationError
+
+	switch {
+	case errors.Is(err, manager.ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, manager.ErrExists):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.As(err, &verr):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, errorResponse{
+		Error: msg,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Errorf("Unable to encode response: %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeManager struct {
+	paths  []config.Path
+	calls  []string
+	probes map[string]bool
+}
+
+func (f *fakeManager) GetCollectors() []prometheus.Collector {
+	return nil
+}
+
+func (f *fakeManager) AddPath(p config.Path) ([]string, error) {
+	f.paths = append(f.paths, p)
+	return []string{p.Name + "@BE"}, nil
+}
+
+func (f *fakeManager) call(action string, id string) error {
+	if !f.probes[id] {
+		return manager.ErrNotFound
+	}
+
+	f.calls = append(f.calls, action+" "+id)
+	return nil
+}
+
+func (f *fakeManager) Remove(id string) error {
+	return f.call("remove", id)
+}
+
+func (f *fakeManager) Pause(id string) error {
+	return f.call("pause", id)
+}
+
+func (f *fakeManager) Resume(id string) error {
+	return f.call("resume", id)
+}
+
+func TestProberAPI(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		method        string
+		path          string
+		body          string
+		auth          string
+		expectedCode  int
+		expectedCalls []string
+	}{
+		{
+			name:         "Test #1: API disabled without token",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/pause",
+			auth:         "Bearer ",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "Test #2: wrong token",
+			token:        "secret",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/pause",
+			auth:         "Bearer wrong",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "Test #3: pause",
+			token:         "secret",
+			method:        http.MethodPost,
+			path:          "/api/v1/probers/a-b%40BE/pause",
+			auth:          "Bearer secret",
+			expectedCode:  http.StatusNoContent,
+			expectedCalls: []string{"pause a-b@BE"},
+		},
+		{
+			name:          "Test #4: remove with escaped slash",
+			token:         "secret",
+			method:        http.MethodDelete,
+			path:          "/api/v1/probers/fra%2Fams@BE",
+			auth:          "Bearer secret",
+			expectedCode:  http.StatusNoContent,
+			expectedCalls: []string{"remove fra/ams@BE"},
+		},
+		{
+			name:         "Test #5: unknown prober",
+			token:        "secret",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/x@BE/resume",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Test #6: add path",
+			token:        "secret",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers",
+			body:         `{"name": "c-d", "hops": ["c", "d"], "pps": 10}`,
+			auth:         "Bearer secret",
+			expectedCode: http.StatusCreated,
+		},
+	}
+
+	for _, test := range tests {
+		m := &fakeManager{
+			probes: map[string]bool{
+				"a-b@BE":     true,
+				"fra/ams@BE": true,
+			},
+		}
+		fe := New(&Config{APIToken: test.token}, m)
+		mux := http.NewServeMux()
+		fe.registerAPI(mux)
+
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		req.Header.Set("Authorization", test.auth)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		assert.Equal(t, test.expectedCalls, m.calls, test.name)
+	}
+}
+
+func TestAddPathRequest(t *testing.T) {
+	m := &fakeManager{}
+	fe := New(&Config{APIToken: "secret"}, m)
+	mux := http.NewServeMux()
+	fe.registerAPI(mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/probers", strings.NewReader(`{"name": "c-d", "hops": ["c", "d"], "classes": ["EF"], "pps": 10}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"ids": ["c-d@BE"]}`, rec.Body.String())
+	assert.Len(t, m.paths, 1)
+	assert.Equal(t, []string{"c", "d"}, m.paths[0].Hops)
+	assert.Equal(t, []string{"EF"}, m.paths[0].Classes)
+	assert.Equal(t, uint64(10), *m.paths[0].PPS)
+}
@@ -0,0 +1,107 @@
+package frontend
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/capture"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	apiCapturesPath = "/api/v1/captures"
+)
+
+// PacketCapture starts and stops pcapng captures of the packets of probers
+type PacketCapture interface {
+	Start(id string, d time.Duration, packets uint64) (capture.Capture, error)
+	Stop(id string) error
+	Active() []capture.Capture
+}
+
+// captureRequest limits a capture. Zero values select the configured maximum.
+type captureRequest struct {
+	DurationMS uint64 `json:"duration_ms"`
+	Packets    uint64 `json:"packets"`
+}
+
+type capturesResponse struct {
+	Captures []capture.Capture `json:"captures"`
+}
+
+func (fe *Frontend) registerCaptures(mux *http.ServeMux) {
+	mux.HandleFunc(apiCapturesPath, fe.handleCapturesRequest)
+}
+
+// handleCapturesRequest lists the active captures
+func (fe *Frontend) handleCapturesRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if fe.cfg.Capturer == nil {
+		writeError(w, http.StatusNotImplemented, "Packet capture is not supported")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, capturesResponse{
+		Captures: fe.cfg.Capturer.Active(),
+	})
+}
+
+// handleCapture starts (POST) or stops (DELETE) the capture of a prober
+func (fe *Frontend) handleCapture(w http.ResponseWriter, r *http.Request, id string) {
+	if !fe.authorize(w, r) {
+		return
+	}
+
+	if fe.cfg.Capturer == nil {
+		writeError(w, http.StatusNotImplemented, "Packet capture is not supported")
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		err := fe.cfg.Capturer.Stop(id)
+		if err != nil {
+			writeCaptureError(w, err)
+			return
+		}
+
+		log.Infof("Prober %q: capture stop requested via API by %s", id, r.RemoteAddr)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	req := captureRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Unable to decode request: "+err.Error())
+		return
+	}
+
+	c, err := fe.cfg.Capturer.Start(id, time.Duration(req.DurationMS)*time.Millisecond, req.Packets)
+	if err != nil {
+		writeCaptureError(w, err)
+		return
+	}
+
+	log.Infof("Prober %q: capture start requested via API by %s", id, r.RemoteAddr)
+	writeJSON(w, http.StatusCreated, c)
+}
+
+func writeCaptureError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, capture.ErrNotFound), errors.Is(err, capture.ErrInactive):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, capture.ErrActive):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, capture.ErrLimit):
+		writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,149 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/capture"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCapturer struct {
+	active map[string]capture.Capture
+}
+
+func (f *fakeCapturer) Start(id string, d time.Duration, packets uint64) (capture.Capture, error) {
+	if id != "a-b@BE" {
+		return capture.Capture{}, capture.ErrNotFound
+	}
+
+	if d > time.Minute {
+		return capture.Capture{}, capture.ErrLimit
+	}
+
+	if _, ok := f.active[id]; ok {
+		return capture.Capture{}, capture.ErrActive
+	}
+
+	c := capture.Capture{
+		Prober:  id,
+		File:    "/tmp/a-b@BE.pcapng",
+		Packets: packets,
+		Until:   time.Unix(0, 0).UTC().Add(d),
+	}
+	f.active[id] = c
+	return c, nil
+}
+
+func (f *fakeCapturer) Stop(id string) error {
+	if _, ok := f.active[id]; !ok {
+		return capture.ErrInactive
+	}
+
+	delete(f.active, id)
+	return nil
+}
+
+func (f *fakeCapturer) Active() []capture.Capture {
+	ret := make([]capture.Capture, 0)
+	for _, c := range f.active {
+		ret = append(ret, c)
+	}
+
+	return ret
+}
+
+func TestCaptureAPI(t *testing.T) {
+	c := &fakeCapturer{
+		active: make(map[string]capture.Capture),
+	}
+	fe := New(&Config{APIToken: "secret", Capturer: c}, &fakeManager{})
+	mux := http.NewServeMux()
+	fe.registerAPI(mux)
+	fe.registerCaptures(mux)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		auth         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Test #1: unauthorized",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Test #2: duration above limit",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			auth:         "Bearer secret",
+			body:         `{"duration_ms": 3600000}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Test #3: start",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			auth:         "Bearer secret",
+			body:         `{"duration_ms": 10000, "packets": 100}`,
+			expectedCode: http.StatusCreated,
+			expectedBody: `{"prober": "a-b@BE", "file": "/tmp/a-b@BE.pcapng", "packets": 100, "until": "1970-01-01T00:00:10Z"}`,
+		},
+		{
+			name:         "Test #4: start active capture",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Test #5: list",
+			method:       http.MethodGet,
+			path:         "/api/v1/captures",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"captures": [{"prober": "a-b@BE", "file": "/tmp/a-b@BE.pcapng", "packets": 100, "until": "1970-01-01T00:00:10Z"}]}`,
+		},
+		{
+			name:         "Test #6: stop",
+			method:       http.MethodDelete,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Test #7: stop inactive capture",
+			method:       http.MethodDelete,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Test #8: invalid request",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/capture",
+			auth:         "Bearer secret",
+			body:         `{"packets": -1}`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		req.Header.Set("Authorization", test.auth)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		if test.expectedBody != "" {
+			assert.JSONEq(t, test.expectedBody, rec.Body.String(), test.name)
+		}
+	}
+}
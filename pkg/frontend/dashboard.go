@@ -0,0 +1,232 @@
+package frontend
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	dashboardProberPath = "/probers/"
+
+	// dashboardHistory is the number of measurements shown in sparklines
+	dashboardHistory = 60
+
+	sparklineWidth  = 120
+	sparklineHeight = 24
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"ms":      formatMS,
+	"percent": formatPercent,
+	"escape":  url.PathEscape,
+	"spark":   newSparkline,
+}).ParseFS(templateFS, "templates/*.html"))
+
+type dashboardPage struct {
+	Version     string
+	MetricsPath string
+	Probers     []dashboardProber
+}
+
+type proberPage struct {
+	Version      string
+	Prober       dashboardProber
+	Status       proberStatus
+	Measurements []measurementStatus
+}
+
+type sparklineData struct {
+	Class  string
+	Points string
+	Width  int
+	Height int
+}
+
+func newSparkline(class string, points string) sparklineData {
+	return sparklineData{
+		Class:  class,
+		Points: points,
+		Width:  sparklineWidth,
+		Height: sparklineHeight,
+	}
+}
+
+type dashboardProber struct {
+	ID       string
+	Path     string
+	Class    string
+	State    string
+	Health   string
+	Current  *measurementStatus
+	LossLine string
+	RTTLine  string
+}
+
+func (fe *Frontend) registerDashboard(mux *http.ServeMux) {
+	mux.HandleFunc("/", fe.handleDashboard)
+	mux.HandleFunc(dashboardProberPath, fe.handleProberPage)
+}
+
+func (fe *Frontend) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page := dashboardPage{
+		Version:     fe.cfg.Version,
+		MetricsPath: fe.cfg.MetricsPath,
+	}
+
+	if i := fe.inspector(); i != nil {
+		for _, s := range i.Status() {
+			measurements, err := i.Measurements(s.ID, dashboardHistory)
+			if err != nil {
+				continue
+			}
+
+			page.Probers = append(page.Probers, newDashboardProber(s, fe.healthState(s), measurements))
+		}
+	}
+
+	renderTemplate(w, "dashboard.html", page)
+}
+
+func (fe *Frontend) handleProberPage(w http.ResponseWriter, r *http.Request) {
+	id, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), dashboardProberPath))
+	if err != nil || id == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	i := fe.inspector()
+	if i == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	for _, s := range i.Status() {
+		if s.ID != id {
+			continue
+		}
+
+		measurements, err := i.Measurements(s.ID, dashboardHistory)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		page := proberPage{
+			Version:      fe.cfg.Version,
+			Prober:       newDashboardProber(s, fe.healthState(s), measurements),
+			Status:       newProberStatus(s, fe.healthState(s)),
+			Measurements: make([]measurementStatus, 0, len(measurements)),
+		}
+
+		// Newest first
+		for j := len(measurements) - 1; j >= 0; j-- {
+			page.Measurements = append(page.Measurements, newMeasurementStatus(measurements[j]))
+		}
+
+		renderTemplate(w, "prober.html", page)
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func renderTemplate(w http.ResponseWriter, name string, data interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	err := templates.ExecuteTemplate(w, name, data)
+	if err != nil {
+		log.Errorf("Unable to render template %q: %v", name, err)
+	}
+}
+
+func newDashboardProber(s manager.ProberStatus, h health.State, measurements []prober.TimedMeasurement) dashboardProber {
+	ret := dashboardProber{
+		ID:     s.ID,
+		Path:   s.Path,
+		Class:  s.Class,
+		State:  proberState(s),
+		Health: h.String(),
+	}
+
+	loss := make([]float64, len(measurements))
+	rtt := make([]float64, len(measurements))
+	for i, m := range measurements {
+		loss[i] = m.Loss()
+		rtt[i] = float64(m.RTTAvg())
+	}
+	ret.LossLine = sparkline(loss, 1)
+	ret.RTTLine = sparkline(rtt, 0)
+
+	if len(measurements) > 0 {
+		cur := newMeasurementStatus(measurements[len(measurements)-1])
+		ret.Current = &cur
+	}
+
+	return ret
+}
+
+// healthState returns the health state of a prober. It is unknown for probers that are not running.
+func (fe *Frontend) healthState(s manager.ProberStatus) health.State {
+	if fe.cfg.Health == nil || !s.Running || s.Paused {
+		return health.StateUnknown
+	}
+
+	return fe.cfg.Health.State(s.ID)
+}
+
+// sparkline returns the points of an SVG polyline for values. The y axis is scaled to
+// the maximum of values and min.
+func sparkline(values []float64, min float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	max := min
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	step := float64(sparklineWidth)
+	if len(values) > 1 {
+		step = float64(sparklineWidth) / float64(len(values)-1)
+	}
+
+	points := make([]string, len(values))
+	for i, v := range values {
+		y := float64(sparklineHeight)
+		if max > 0 {
+			y -= v / max * sparklineHeight
+		}
+
+		points[i] = fmt.Sprintf("%.1f,%.1f", float64(i)*step, y)
+	}
+
+	return strings.Join(points, " ")
+}
+
+func formatMS(ns uint64) string {
+	return fmt.Sprintf("%.2f ms", float64(ns)/float64(time.Millisecond))
+}
+
+func formatPercent(ratio float64) string {
+	return fmt.Sprintf("%.2f%%", ratio*100)
+}
@@ -0,0 +1,85 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHealth map[string]health.State
+
+func (f fakeHealth) State(id string) health.State {
+	return f[id]
+}
+
+func TestDashboard(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		expectedCode int
+		contains     []string
+	}{
+		{
+			name:         "Test #1: overview",
+			path:         "/",
+			expectedCode: http.StatusOK,
+			contains: []string{
+				`<a href="/probers/a-b@BE">a-b</a>`,
+				`class="health down"`,
+				`100.00%`,
+				`<polyline points="0.0,18.0 120.0,0.0"/>`,
+			},
+		},
+		{
+			name:         "Test #2: prober page",
+			path:         "/probers/a-b%40BE",
+			expectedCode: http.StatusOK,
+			contains: []string{
+				"a-b (BE)",
+				"10.1.0.0 - 10.1.0.1 (2)",
+				"1970-01-01 00:00:01",
+				"0.00 ms",
+			},
+		},
+		{
+			name:         "Test #3: unknown prober",
+			path:         "/probers/x%40BE",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Test #4: unknown page",
+			path:         "/foo",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		m := &fakeManager{
+			probes: map[string]bool{
+				"a-b@BE": true,
+			},
+		}
+		fe := New(&Config{MetricsPath: "/metrics", Health: fakeHealth{"a-b@BE": health.StateDown}}, m)
+		mux := http.NewServeMux()
+		fe.registerDashboard(mux)
+
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		for _, c := range test.contains {
+			assert.Contains(t, rec.Body.String(), c, test.name)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	assert.Equal(t, "", sparkline(nil, 0))
+	assert.Equal(t, "0.0,24.0", sparkline([]float64{0}, 0))
+	assert.Equal(t, "0.0,24.0 60.0,12.0 120.0,0.0", sparkline([]float64{0, 1, 2}, 0))
+	assert.Equal(t, "0.0,24.0 120.0,18.0", sparkline([]float64{0, 0.25}, 1))
+}
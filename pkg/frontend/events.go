@@ -0,0 +1,63 @@
+package frontend
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/events"
+)
+
+const (
+	apiEventsPath = "/api/v1/events"
+)
+
+// EventLog provides the history of events
+type EventLog interface {
+	Query(path string, class string, since time.Time) []events.Event
+}
+
+type eventsResponse struct {
+	Events []events.Event `json:"events"`
+}
+
+func (fe *Frontend) registerEvents(mux *http.ServeMux) {
+	mux.HandleFunc(apiEventsPath, fe.handleEventsRequest)
+}
+
+// handleEventsRequest returns the events matching the optional path, class and since parameters.
+// since is either an RFC 3339 timestamp or a duration relative to now (e.g. 1h).
+func (fe *Frontend) handleEventsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if fe.cfg.Events == nil {
+		writeError(w, http.StatusNotImplemented, "Events are not supported")
+		return
+	}
+
+	q := r.URL.Query()
+	since, err := parseSince(q.Get("since"), time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Parameter since must be an RFC 3339 timestamp or a duration")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, eventsResponse{
+		Events: fe.cfg.Events.Query(q.Get("path"), q.Get("class"), since),
+	})
+}
+
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err == nil {
+		return now.Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
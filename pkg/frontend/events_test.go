@@ -0,0 +1,62 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsRequest(t *testing.T) {
+	l, err := events.New(events.Config{
+		Size: 10,
+	})
+	assert.NoError(t, err)
+
+	l.Add(events.Event{Time: time.Unix(1, 0).UTC(), Type: events.TypeLossOnset, Prober: "a-b@BE", Path: "a-b", Class: "BE", Message: "Loss started"})
+	l.Add(events.Event{Time: time.Unix(2, 0).UTC(), Type: events.TypeLossOnset, Prober: "c-d@BE", Path: "c-d", Class: "BE", Message: "Loss started"})
+	l.Add(events.Event{Time: time.Unix(3, 0).UTC(), Type: events.TypeLossEnd, Prober: "a-b@BE", Path: "a-b", Class: "BE", Message: "Loss ended"})
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Test #1: filter by path and since",
+			query:        "?path=a-b&since=1970-01-01T00:00:02Z",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"events": [{"time": "1970-01-01T00:00:03Z", "type": "loss_end", "prober": "a-b@BE", "path": "a-b", "class": "BE", "message": "Loss ended"}]}`,
+		},
+		{
+			name:         "Test #2: relative since",
+			query:        "?since=1h",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"events": []}`,
+		},
+		{
+			name:         "Test #3: invalid since",
+			query:        "?since=yesterday",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		fe := New(&Config{Events: l}, &fakeManager{})
+		mux := http.NewServeMux()
+		fe.registerEvents(mux)
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/events"+test.query, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		if test.expectedBody != "" {
+			assert.JSONEq(t, test.expectedBody, rec.Body.String(), test.name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package frontend
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,12 +22,21 @@ type Config struct {
 	Version       string
 	MetricsPath   string
 	ListenAddress string
+	Reload        func() error
+	APIToken      string         // Token required to change probers. The API is read only if empty.
+	OpenReload    bool           // Allow /-/reload without a token if APIToken is empty
+	Health        HealthTracker  // Optional source of the health state shown for probers
+	Events        EventLog       // Optional history of events
+	Suspects      SuspectFinder  // Optional correlation of failing paths
+	Recorder      ProbeRecording // Optional raw probe recorder
+	Capturer      PacketCapture  // Optional pcapng capture of probe packets
 }
 
 // Frontend represents an HTTP prometheus interface
 type Frontend struct {
 	cfg       *Config
 	proberReg ProberRegistry
+	srv       *http.Server
 }
 
 // New creates a new HTTP frontend
@@ -33,27 +44,35 @@ func New(cfg *Config, proberReg ProberRegistry) *Frontend {
 	return &Frontend{
 		cfg:       cfg,
 		proberReg: proberReg,
+		srv: &http.Server{
+			Addr: cfg.ListenAddress,
+		},
 	}
 }
 
 // Start starts the frontend
 func (fe *Frontend) Start() {
 	log.Infof("Starting Matroschka Prober (Version: %s)\n", fe.cfg.Version)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte(`<html>
-			<head><title>Matroschka Prober (Version ` + fe.cfg.Version + `)</title></head>
-			<body>
-			<h1>Matroschka Prober</h1>
-			<p><a href="` + fe.cfg.MetricsPath + `">Metrics</a></p>
-			<h2>More information:</h2>
-			<p><a href="https://github.com/exaring/matroschka-prober">github.com/exaring/matroschka-prober</a></p>
-			</body>
-			</html>`))
-	})
 	http.HandleFunc(fe.cfg.MetricsPath, fe.handleMetricsRequest)
+	http.HandleFunc("/-/reload", fe.handleReloadRequest)
+	fe.registerAPI(http.DefaultServeMux)
+	fe.registerDashboard(http.DefaultServeMux)
+	fe.registerMatrix(http.DefaultServeMux)
+	fe.registerEvents(http.DefaultServeMux)
+	fe.registerSuspects(http.DefaultServeMux)
+	fe.registerRecordings(http.DefaultServeMux)
+	fe.registerCaptures(http.DefaultServeMux)
 
 	log.Infof("Listening for %s on %s\n", fe.cfg.MetricsPath, fe.cfg.ListenAddress)
-	log.Fatal(http.ListenAndServe(fe.cfg.ListenAddress, nil))
+	err := fe.srv.ListenAndServe()
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
+
+// Shutdown gracefully shuts down the frontend
+func (fe *Frontend) Shutdown(ctx context.Context) error {
+	return fe.srv.Shutdown(ctx)
 }
 
 func (fe *Frontend) handleMetricsRequest(w http.ResponseWriter, r *http.Request) {
@@ -67,6 +86,32 @@ func (fe *Frontend) handleMetricsRequest(w http.ResponseWriter, r *http.Request)
 		ErrorHandling: promhttp.ContinueOnError}).ServeHTTP(w, r)
 }
 
+func (fe *Frontend) handleReloadRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "Only POST or PUT requests allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if fe.cfg.APIToken != "" || !fe.cfg.OpenReload {
+		if !fe.authorize(w, r) {
+			return
+		}
+	}
+
+	if fe.cfg.Reload == nil {
+		http.Error(w, "Reloading is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	log.Infof("Reloading config as requested by %s", r.RemoteAddr)
+	err := fe.cfg.Reload()
+	if err != nil {
+		log.Errorf("Unable to reload config: %v", err)
+		http.Error(w, fmt.Sprintf("Unable to reload config: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
 type errLogger struct {
 	*log.Logger
 }
@@ -0,0 +1,64 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadRequest(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		openReload     bool
+		auth           string
+		expectedCode   int
+		expectedReload bool
+	}{
+		{
+			name:         "Test #1: refused without token",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:           "Test #2: explicitly allowed without token",
+			openReload:     true,
+			expectedCode:   http.StatusOK,
+			expectedReload: true,
+		},
+		{
+			name:         "Test #3: token is required if configured",
+			token:        "secret",
+			openReload:   true,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:           "Test #4: authorized",
+			token:          "secret",
+			auth:           "Bearer secret",
+			expectedCode:   http.StatusOK,
+			expectedReload: true,
+		},
+	}
+
+	for _, test := range tests {
+		reloaded := false
+		fe := New(&Config{
+			APIToken:   test.token,
+			OpenReload: test.openReload,
+			Reload: func() error {
+				reloaded = true
+				return nil
+			},
+		}, &fakeManager{})
+
+		req := httptest.NewRequest(http.MethodPost, "/-/reload", nil)
+		req.Header.Set("Authorization", test.auth)
+		rec := httptest.NewRecorder()
+		fe.handleReloadRequest(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		assert.Equal(t, test.expectedReload, reloaded, test.name)
+	}
+}
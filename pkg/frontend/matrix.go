@@ -0,0 +1,238 @@
+package frontend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+)
+
+const (
+	apiMatrixPath = "/api/v1/matrix"
+	matrixPath    = "/matrix"
+
+	groupByRouter = "router"
+	groupByTag    = "tag"
+
+	// untaggedGroup is the group of routers without tags when grouping by tag
+	untaggedGroup = "untagged"
+)
+
+// RouterInventory provides the routers of the current config
+type RouterInventory interface {
+	Routers() []config.Router
+}
+
+// matrix arranges the last measurements of all probers of a class by first and last hop
+type matrix struct {
+	Class   string          `json:"class"`
+	Classes []string        `json:"classes"`
+	GroupBy string          `json:"group_by"`
+	Rows    []string        `json:"rows"`
+	Columns []string        `json:"columns"`
+	Cells   [][]*matrixCell `json:"cells"`
+}
+
+type matrixCell struct {
+	Probers  []string `json:"probers"`
+	Sent     uint64   `json:"sent"`
+	Received uint64   `json:"received"`
+	Loss     float64  `json:"loss_ratio"`
+	RTTAvg   uint64   `json:"rtt_avg_ns"`
+	Health   string   `json:"health"`
+	rttSum   uint64
+	state    health.State
+}
+
+type matrixPage struct {
+	Version string
+	Matrix  *matrix
+}
+
+func (fe *Frontend) registerMatrix(mux *http.ServeMux) {
+	mux.HandleFunc(apiMatrixPath, fe.handleMatrixRequest)
+	mux.HandleFunc(matrixPath, fe.handleMatrixPage)
+}
+
+func (fe *Frontend) handleMatrixRequest(w http.ResponseWriter, r *http.Request) {
+	m, code, err := fe.matrix(r)
+	if err != nil {
+		writeError(w, code, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (fe *Frontend) handleMatrixPage(w http.ResponseWriter, r *http.Request) {
+	m, code, err := fe.matrix(r)
+	if err != nil {
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	renderTemplate(w, "matrix.html", matrixPage{
+		Version: fe.cfg.Version,
+		Matrix:  m,
+	})
+}
+
+// matrix builds the matrix requested by the class and group_by parameters of r
+func (fe *Frontend) matrix(r *http.Request) (*matrix, int, error) {
+	if r.Method != http.MethodGet {
+		return nil, http.StatusMethodNotAllowed, errors.New("Method not allowed")
+	}
+
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = groupByRouter
+	}
+
+	if groupBy != groupByRouter && groupBy != groupByTag {
+		return nil, http.StatusBadRequest, fmt.Errorf("Parameter group_by must be %q or %q", groupByRouter, groupByTag)
+	}
+
+	i := fe.inspector()
+	if i == nil {
+		return nil, http.StatusNotImplemented, errors.New("Prober status is not supported")
+	}
+
+	var routers []config.Router
+	if inv, ok := fe.proberReg.(RouterInventory); ok {
+		routers = inv.Routers()
+	}
+
+	return buildMatrix(i, routers, r.URL.Query().Get("class"), groupBy, fe.healthState), http.StatusOK, nil
+}
+
+func buildMatrix(i ProberInspector, routers []config.Router, class string, groupBy string, stateOf func(manager.ProberStatus) health.State) *matrix {
+	status := i.Status()
+
+	classes := make(map[string]struct{})
+	for _, s := range status {
+		classes[s.Class] = struct{}{}
+	}
+
+	ret := &matrix{
+		Classes: sortedKeys(classes),
+		GroupBy: groupBy,
+	}
+
+	ret.Class = class
+	if ret.Class == "" && len(ret.Classes) > 0 {
+		ret.Class = ret.Classes[0]
+	}
+
+	groups := routerGroups(routers, groupBy)
+	cells := make(map[string]map[string]*matrixCell)
+	rows := make(map[string]struct{})
+	cols := make(map[string]struct{})
+	for _, s := range status {
+		if s.Class != ret.Class || len(s.Config.Hops) == 0 {
+			continue
+		}
+
+		measurements, err := i.Measurements(s.ID, 1)
+		if err != nil {
+			continue
+		}
+
+		state := stateOf(s)
+		first := groups(s.Config.Hops[0].Name)
+		last := groups(s.Config.Hops[len(s.Config.Hops)-1].Name)
+		for _, row := range first {
+			for _, col := range last {
+				rows[row] = struct{}{}
+				cols[col] = struct{}{}
+
+				if cells[row] == nil {
+					cells[row] = make(map[string]*matrixCell)
+				}
+
+				c := cells[row][col]
+				if c == nil {
+					c = &matrixCell{}
+					cells[row][col] = c
+				}
+
+				c.Probers = append(c.Probers, s.ID)
+				if state > c.state {
+					c.state = state
+				}
+
+				if len(measurements) > 0 {
+					c.Sent += measurements[0].Sent
+					c.Received += measurements[0].Received
+					c.rttSum += measurements[0].RTTSum
+				}
+			}
+		}
+	}
+
+	ret.Rows = sortedKeys(rows)
+	ret.Columns = sortedKeys(cols)
+	ret.Cells = make([][]*matrixCell, len(ret.Rows))
+	for j, row := range ret.Rows {
+		ret.Cells[j] = make([]*matrixCell, len(ret.Columns))
+		for k, col := range ret.Columns {
+			c := cells[row][col]
+			if c == nil {
+				continue
+			}
+
+			c.finish()
+			ret.Cells[j][k] = c
+		}
+	}
+
+	return ret
+}
+
+// finish calculates the aggregated values of a cell. Its health is the worst state of its probers.
+func (c *matrixCell) finish() {
+	if c.Sent > 0 && c.Received < c.Sent {
+		c.Loss = float64(c.Sent-c.Received) / float64(c.Sent)
+	}
+
+	if c.Received > 0 {
+		c.RTTAvg = c.rttSum / c.Received
+	}
+
+	c.Health = c.state.String()
+}
+
+// routerGroups returns a function mapping a router name to the groups it belongs to
+func routerGroups(routers []config.Router, groupBy string) func(string) []string {
+	if groupBy != groupByTag {
+		return func(name string) []string {
+			return []string{name}
+		}
+	}
+
+	tags := make(map[string][]string)
+	for _, r := range routers {
+		tags[r.Name] = r.Tags
+	}
+
+	return func(name string) []string {
+		if len(tags[name]) == 0 {
+			return []string{untaggedGroup}
+		}
+
+		return tags[name]
+	}
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
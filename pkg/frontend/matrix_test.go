@@ -0,0 +1,195 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeInventory struct {
+	status       []manager.ProberStatus
+	measurements map[string]*measurement.Measurement
+	routers      []config.Router
+	states       map[string]health.State
+}
+
+func (f *fakeInventory) GetCollectors() []prometheus.Collector {
+	return nil
+}
+
+func (f *fakeInventory) Status() []manager.ProberStatus {
+	return f.status
+}
+
+func (f *fakeInventory) Measurements(id string, n int) ([]prober.TimedMeasurement, error) {
+	m, ok := f.measurements[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return []prober.TimedMeasurement{
+		{
+			Measurement: m,
+		},
+	}, nil
+}
+
+func (f *fakeInventory) Routers() []config.Router {
+	return f.routers
+}
+
+func (f *fakeInventory) state(s manager.ProberStatus) health.State {
+	return f.states[s.ID]
+}
+
+func fakeProberStatus(class string, hops ...string) manager.ProberStatus {
+	cfg := prober.Config{}
+	name := ""
+	for i, h := range hops {
+		cfg.Hops = append(cfg.Hops, prober.Hop{
+			Name: h,
+		})
+
+		if i > 0 {
+			name += "-"
+		}
+		name += h
+	}
+
+	return manager.ProberStatus{
+		ID:     name + "@" + class,
+		Path:   name,
+		Class:  class,
+		Config: cfg,
+	}
+}
+
+func newFakeInventory() *fakeInventory {
+	return &fakeInventory{
+		status: []manager.ProberStatus{
+			fakeProberStatus("BE", "a", "b"),
+			fakeProberStatus("BE", "a", "c"),
+			fakeProberStatus("BE", "b", "x", "a"),
+			fakeProberStatus("EF", "a", "b"),
+		},
+		measurements: map[string]*measurement.Measurement{
+			"a-b@BE":   {Sent: 100, Received: 100, RTTSum: 100000},
+			"a-c@BE":   {Sent: 100, Received: 50, RTTSum: 100000},
+			"b-x-a@BE": {Sent: 100, Received: 99, RTTSum: 99000},
+		},
+		routers: []config.Router{
+			{Name: "a", Tags: []string{"fra"}},
+			{Name: "b", Tags: []string{"ams"}},
+			{Name: "c", Tags: []string{"ams"}},
+		},
+		states: map[string]health.State{
+			"a-b@BE":   health.StateUp,
+			"a-c@BE":   health.StateDown,
+			"b-x-a@BE": health.StateDegraded,
+		},
+	}
+}
+
+func TestBuildMatrix(t *testing.T) {
+	tests := []struct {
+		name     string
+		class    string
+		groupBy  string
+		expected *matrix
+	}{
+		{
+			name:    "Test #1: by router with default class",
+			groupBy: groupByRouter,
+			expected: &matrix{
+				Class:   "BE",
+				Classes: []string{"BE", "EF"},
+				GroupBy: groupByRouter,
+				Rows:    []string{"a", "b"},
+				Columns: []string{"a", "b", "c"},
+				Cells: [][]*matrixCell{
+					{
+						nil,
+						{Probers: []string{"a-b@BE"}, Sent: 100, Received: 100, RTTAvg: 1000, Health: "up", rttSum: 100000, state: health.StateUp},
+						{Probers: []string{"a-c@BE"}, Sent: 100, Received: 50, Loss: 0.5, RTTAvg: 2000, Health: "down", rttSum: 100000, state: health.StateDown},
+					},
+					{
+						{Probers: []string{"b-x-a@BE"}, Sent: 100, Received: 99, Loss: 0.01, RTTAvg: 1000, Health: "degraded", rttSum: 99000, state: health.StateDegraded},
+						nil,
+						nil,
+					},
+				},
+			},
+		},
+		{
+			name:    "Test #2: by tag",
+			class:   "BE",
+			groupBy: groupByTag,
+			expected: &matrix{
+				Class:   "BE",
+				Classes: []string{"BE", "EF"},
+				GroupBy: groupByTag,
+				Rows:    []string{"ams", "fra"},
+				Columns: []string{"ams", "fra"},
+				Cells: [][]*matrixCell{
+					{
+						nil,
+						{Probers: []string{"b-x-a@BE"}, Sent: 100, Received: 99, Loss: 0.01, RTTAvg: 1000, Health: "degraded", rttSum: 99000, state: health.StateDegraded},
+					},
+					{
+						{Probers: []string{"a-b@BE", "a-c@BE"}, Sent: 200, Received: 150, Loss: 0.25, RTTAvg: 1333, Health: "down", rttSum: 200000, state: health.StateDown},
+						nil,
+					},
+				},
+			},
+		},
+		{
+			name:    "Test #3: class without measurements",
+			class:   "EF",
+			groupBy: groupByRouter,
+			expected: &matrix{
+				Class:   "EF",
+				Classes: []string{"BE", "EF"},
+				GroupBy: groupByRouter,
+				Rows:    []string{"a"},
+				Columns: []string{"b"},
+				Cells: [][]*matrixCell{
+					{
+						{Probers: []string{"a-b@EF"}, Health: "unknown"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		f := newFakeInventory()
+		m := buildMatrix(f, f.routers, test.class, test.groupBy, f.state)
+		assert.Equal(t, test.expected, m, test.name)
+	}
+}
+
+func TestMatrixRequest(t *testing.T) {
+	fe := New(&Config{}, newFakeInventory())
+	mux := http.NewServeMux()
+	fe.registerMatrix(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/matrix?class=BE&group_by=foo", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/matrix?class=BE", nil)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `<a href="/probers/a-c@BE" title="a-c@BE">`)
+	assert.Contains(t, rec.Body.String(), "50.00%")
+}
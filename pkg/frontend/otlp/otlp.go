@@ -0,0 +1,310 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	// ProtocolGRPC is OTLP over gRPC
+	ProtocolGRPC = "grpc"
+	// ProtocolHTTP is OTLP over HTTP with protobuf encoding
+	ProtocolHTTP = "http"
+
+	metricPrefix = "matroschka_"
+	serviceName  = "matroschka-prober"
+)
+
+// rttBoundaries are the histogram bucket boundaries of the RTT in nanoseconds
+var rttBoundaries = []float64{
+	1e5, 2.5e5, 5e5, 1e6, 2.5e6, 5e6, 1e7, 2.5e7, 5e7, 1e8, 2.5e8, 5e8, 1e9,
+}
+
+// Config is the configuration of the OTLP exporter
+type Config struct {
+	Endpoint string // host:port of the receiver
+	Protocol string
+	Insecure bool
+	Headers  map[string]string
+	Timeout  time.Duration
+	Version  string
+}
+
+// Exporter pushes the measurements of all probers to an OTLP receiver after every finished bucket
+type Exporter struct {
+	exporter sdkmetric.Exporter
+	reader   sdkmetric.Reader
+	provider *sdkmetric.MeterProvider
+	timeout  time.Duration
+	flush    chan struct{}
+
+	sent     metric.Int64Counter
+	received metric.Int64Counter
+	rttMin   metric.Int64ObservableGauge
+	rttMax   metric.Int64ObservableGauge
+	rttAvg   metric.Int64ObservableGauge
+	loss     metric.Float64ObservableGauge
+	rtt      metric.Int64Histogram
+
+	// latest holds the gauge values of the last finished bucket per prober
+	latest   map[string]*gauges
+	latestMu sync.Mutex
+}
+
+type gauges struct {
+	attrs  attribute.Set
+	hasRTT bool
+	rttMin int64
+	rttMax int64
+	rttAvg int64
+	loss   *float64
+}
+
+// New creates a new OTLP exporter
+func New(ctx context.Context, cfg Config) (*Exporter, error) {
+	exp, err := newExporter(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create OTLP exporter: %v", err)
+	}
+
+	reader := sdkmetric.NewManualReader(
+		sdkmetric.WithTemporalitySelector(exp.Temporality),
+		sdkmetric.WithAggregationSelector(exp.Aggregation),
+	)
+
+	res := resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+		attribute.String("service.version", cfg.Version),
+	)
+
+	rttView := sdkmetric.NewView(
+		sdkmetric.Instrument{Name: metricPrefix + "rtt"},
+		sdkmetric.Stream{Aggregation: aggregation.ExplicitBucketHistogram{Boundaries: rttBoundaries}},
+	)
+
+	e := &Exporter{
+		exporter: exp,
+		reader:   reader,
+		provider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader), sdkmetric.WithResource(res), sdkmetric.WithView(rttView)),
+		timeout:  cfg.Timeout,
+		flush:    make(chan struct{}, 1),
+		latest:   make(map[string]*gauges),
+	}
+
+	err = e.createInstruments()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create instruments: %v", err)
+	}
+
+	return e, nil
+}
+
+func newExporter(ctx context.Context, cfg Config) (sdkmetric.Exporter, error) {
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		opts := []otlpmetricgrpc.Option{
+			otlpmetricgrpc.WithEndpoint(cfg.Endpoint),
+			otlpmetricgrpc.WithHeaders(cfg.Headers),
+			otlpmetricgrpc.WithTimeout(cfg.Timeout),
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+
+		return otlpmetricgrpc.New(ctx, opts...)
+	case ProtocolHTTP:
+		opts := []otlpmetrichttp.Option{
+			otlpmetrichttp.WithEndpoint(cfg.Endpoint),
+			otlpmetrichttp.WithHeaders(cfg.Headers),
+			otlpmetrichttp.WithTimeout(cfg.Timeout),
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+
+		return otlpmetrichttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("Unknown protocol %q", cfg.Protocol)
+	}
+}
+
+func (e *Exporter) createInstruments() error {
+	meter := e.provider.Meter(serviceName)
+
+	var err error
+	e.sent, err = meter.Int64Counter(metricPrefix+"packets_sent", metric.WithDescription("Sent packets"))
+	if err != nil {
+		return err
+	}
+
+	e.received, err = meter.Int64Counter(metricPrefix+"packets_received", metric.WithDescription("Received packets"))
+	if err != nil {
+		return err
+	}
+
+	e.rttMin, err = meter.Int64ObservableGauge(metricPrefix+"rtt_min", metric.WithDescription("round-trip time minimum in nanoseconds"), metric.WithUnit("ns"))
+	if err != nil {
+		return err
+	}
+
+	e.rttMax, err = meter.Int64ObservableGauge(metricPrefix+"rtt_max", metric.WithDescription("round-trip time maximum in nanoseconds"), metric.WithUnit("ns"))
+	if err != nil {
+		return err
+	}
+
+	e.rttAvg, err = meter.Int64ObservableGauge(metricPrefix+"rtt_avg", metric.WithDescription("round-trip time average in nanoseconds"), metric.WithUnit("ns"))
+	if err != nil {
+		return err
+	}
+
+	e.loss, err = meter.Float64ObservableGauge(metricPrefix+"loss_ratio", metric.WithDescription("Ratio of lost packets of the last finished bucket"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(e.observeGauges, e.rttMin, e.rttMax, e.rttAvg, e.loss)
+	if err != nil {
+		return err
+	}
+
+	e.rtt, err = meter.Int64Histogram(metricPrefix+"rtt", metric.WithDescription("round-trip time in nanoseconds"), metric.WithUnit("ns"))
+	return err
+}
+
+// observeGauges reports the gauge values of the last finished buckets on collection
+func (e *Exporter) observeGauges(ctx context.Context, o metric.Observer) error {
+	e.latestMu.Lock()
+	defer e.latestMu.Unlock()
+
+	for _, g := range e.latest {
+		attrs := metric.WithAttributeSet(g.attrs)
+		if g.loss != nil {
+			o.ObserveFloat64(e.loss, *g.loss, attrs)
+		}
+
+		if g.hasRTT {
+			o.ObserveInt64(e.rttMin, g.rttMin, attrs)
+			o.ObserveInt64(e.rttMax, g.rttMax, attrs)
+			o.ObserveInt64(e.rttAvg, g.rttAvg, attrs)
+		}
+	}
+
+	return nil
+}
+
+// Observe records a finished measurement. It is pushed with the next export.
+func (e *Exporter) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	ctx := context.Background()
+	set := attributes(p)
+	attrs := metric.WithAttributeSet(set)
+
+	e.sent.Add(ctx, int64(m.Sent), attrs)
+	e.received.Add(ctx, int64(m.Received), attrs)
+	for _, rtt := range m.RTTs {
+		e.rtt.Record(ctx, int64(rtt), attrs)
+	}
+
+	e.latestMu.Lock()
+	g := e.latest[p.ID()]
+	if g == nil {
+		g = &gauges{}
+		e.latest[p.ID()] = g
+	}
+	g.attrs = set
+
+	if m.Sent > 0 {
+		loss := m.Loss()
+		g.loss = &loss
+	}
+
+	if m.Received > 0 {
+		g.hasRTT = true
+		g.rttMin = int64(m.RTTMin)
+		g.rttMax = int64(m.RTTMax)
+		g.rttAvg = int64(m.RTTAvg())
+	}
+	e.latestMu.Unlock()
+
+	select {
+	case e.flush <- struct{}{}:
+	default:
+	}
+}
+
+// Forget drops the gauge values of a removed prober
+func (e *Exporter) Forget(id string) {
+	e.latestMu.Lock()
+	defer e.latestMu.Unlock()
+
+	delete(e.latest, id)
+}
+
+// attributes returns the metric labels of a prober as attributes
+func attributes(p *prober.Prober) attribute.Set {
+	keys, values := p.MetricLabels()
+	kvs := make([]attribute.KeyValue, len(keys))
+	for i := range keys {
+		kvs[i] = attribute.String(keys[i], values[i])
+	}
+
+	return attribute.NewSet(kvs...)
+}
+
+// Run exports recorded measurements until ctx is cancelled. Buckets of all probers finishing
+// at about the same time are sent in one export.
+func (e *Exporter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			e.shutdown()
+			return
+		case <-e.flush:
+			err := e.export(ctx)
+			if err != nil {
+				log.Errorf("Unable to export metrics via OTLP: %v", err)
+			}
+		}
+	}
+}
+
+func (e *Exporter) export(ctx context.Context) error {
+	rm := &metricdata.ResourceMetrics{}
+	err := e.reader.Collect(ctx, rm)
+	if err != nil {
+		return fmt.Errorf("Unable to collect metrics: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	return e.exporter.Export(ctx, rm)
+}
+
+func (e *Exporter) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.timeout)
+	defer cancel()
+
+	err := e.export(ctx)
+	if err != nil {
+		log.Errorf("Unable to export metrics via OTLP: %v", err)
+	}
+
+	err = e.exporter.Shutdown(ctx)
+	if err != nil {
+		log.Errorf("Unable to shut down OTLP exporter: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package otlp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// receiver is a stand-in for an OTLP receiver
+type receiver struct {
+	colmetricpb.UnimplementedMetricsServiceServer
+	requests chan *colmetricpb.ExportMetricsServiceRequest
+}
+
+func newReceiver() *receiver {
+	return &receiver{
+		requests: make(chan *colmetricpb.ExportMetricsServiceRequest, 10),
+	}
+}
+
+func (r *receiver) Export(ctx context.Context, req *colmetricpb.ExportMetricsServiceRequest) (*colmetricpb.ExportMetricsServiceResponse, error) {
+	r.requests <- req
+	return &colmetricpb.ExportMetricsServiceResponse{}, nil
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m := &colmetricpb.ExportMetricsServiceRequest{}
+	err = proto.Unmarshal(body, m)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.requests <- m
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+func startGRPC(t *testing.T, r *receiver) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	s := grpc.NewServer()
+	colmetricpb.RegisterMetricsServiceServer(s, r)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	return l.Addr().String()
+}
+
+func startHTTP(t *testing.T, r *receiver) string {
+	s := httptest.NewServer(r)
+	t.Cleanup(s.Close)
+
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func TestExporter(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		start    func(*testing.T, *receiver) string
+	}{
+		{
+			name:     "Test #1: gRPC",
+			protocol: ProtocolGRPC,
+			start:    startGRPC,
+		},
+		{
+			name:     "Test #2: HTTP",
+			protocol: ProtocolHTTP,
+			start:    startHTTP,
+		},
+	}
+
+	p, err := prober.New(prober.Config{
+		PathName: "a-b",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		Hops: []prober.Hop{
+			{Name: "a"},
+			{Name: "b"},
+		},
+	})
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		r := newReceiver()
+		endpoint := test.start(t, r)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		e, err := New(ctx, Config{
+			Endpoint: endpoint,
+			Protocol: test.protocol,
+			Insecure: true,
+			Timeout:  5 * time.Second,
+		})
+		if !assert.NoError(t, err, test.name) {
+			cancel()
+			continue
+		}
+
+		go e.Run(ctx)
+		e.Observe(p, 0, &measurement.Measurement{
+			Sent:     4,
+			Received: 3,
+			RTTMin:   1000,
+			RTTMax:   3000,
+			RTTSum:   6000,
+			RTTs:     []uint64{1000, 2000, 3000},
+		})
+
+		select {
+		case req := <-r.requests:
+			checkRequest(t, test.name, req)
+		case <-time.After(10 * time.Second):
+			t.Errorf("%s: no export received", test.name)
+		}
+
+		cancel()
+	}
+}
+
+func checkRequest(t *testing.T, name string, req *colmetricpb.ExportMetricsServiceRequest) {
+	if !assert.Len(t, req.ResourceMetrics, 1, name) || !assert.Len(t, req.ResourceMetrics[0].ScopeMetrics, 1, name) {
+		return
+	}
+
+	names := make([]string, 0)
+	for _, m := range req.ResourceMetrics[0].ScopeMetrics[0].Metrics {
+		names = append(names, m.Name)
+
+		switch m.Name {
+		case "matroschka_packets_sent":
+			dp := m.GetSum().DataPoints[0]
+			assert.Equal(t, int64(4), dp.GetAsInt(), name)
+
+			attrs := make(map[string]string)
+			for _, kv := range dp.Attributes {
+				attrs[kv.Key] = kv.Value.GetStringValue()
+			}
+			assert.Equal(t, map[string]string{"path": "a-b", "tos": "BE"}, attrs, name)
+		case "matroschka_loss_ratio":
+			assert.Equal(t, 0.25, m.GetGauge().DataPoints[0].GetAsDouble(), name)
+		case "matroschka_rtt_avg":
+			assert.Equal(t, int64(2000), m.GetGauge().DataPoints[0].GetAsInt(), name)
+		case "matroschka_rtt":
+			dp := m.GetHistogram().DataPoints[0]
+			assert.Equal(t, uint64(3), dp.Count, name)
+			assert.Equal(t, float64(6000), dp.GetSum(), name)
+		}
+	}
+
+	sort.Strings(names)
+	assert.Equal(t, []string{
+		"matroschka_loss_ratio",
+		"matroschka_packets_received",
+		"matroschka_packets_sent",
+		"matroschka_rtt",
+		"matroschka_rtt_avg",
+		"matroschka_rtt_max",
+		"matroschka_rtt_min",
+	}, names, name)
+}
@@ -0,0 +1,82 @@
+package frontend
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/exaring/matroschka-prober/pkg/recorder"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	apiRecordingsPath = "/api/v1/recordings"
+)
+
+// ProbeRecording starts and stops the recording of raw probes of probers
+type ProbeRecording interface {
+	Start(id string) error
+	Stop(id string) error
+	Active() []string
+}
+
+type recordingsResponse struct {
+	Probers []string `json:"probers"`
+}
+
+func (fe *Frontend) registerRecordings(mux *http.ServeMux) {
+	mux.HandleFunc(apiRecordingsPath, fe.handleRecordingsRequest)
+}
+
+// handleRecordingsRequest lists the probers that are recorded
+func (fe *Frontend) handleRecordingsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if fe.cfg.Recorder == nil {
+		writeError(w, http.StatusNotImplemented, "Probe recording is not supported")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, recordingsResponse{
+		Probers: fe.cfg.Recorder.Active(),
+	})
+}
+
+// handleRecording starts (POST) or stops (DELETE) the recording of a prober
+func (fe *Frontend) handleRecording(w http.ResponseWriter, r *http.Request, id string) {
+	if !fe.authorize(w, r) {
+		return
+	}
+
+	if fe.cfg.Recorder == nil {
+		writeError(w, http.StatusNotImplemented, "Probe recording is not supported")
+		return
+	}
+
+	var err error
+	action := "start"
+	if r.Method == http.MethodDelete {
+		action = "stop"
+		err = fe.cfg.Recorder.Stop(id)
+	} else {
+		err = fe.cfg.Recorder.Start(id)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, recorder.ErrNotFound), errors.Is(err, recorder.ErrInactive):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, recorder.ErrActive):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Infof("Prober %q: recording %s requested via API by %s", id, action, r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
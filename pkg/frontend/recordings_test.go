@@ -0,0 +1,118 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/recorder"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRecorder struct {
+	active map[string]bool
+}
+
+func (f *fakeRecorder) Start(id string) error {
+	if id != "a-b@BE" {
+		return recorder.ErrNotFound
+	}
+
+	if f.active[id] {
+		return recorder.ErrActive
+	}
+
+	f.active[id] = true
+	return nil
+}
+
+func (f *fakeRecorder) Stop(id string) error {
+	if !f.active[id] {
+		return recorder.ErrInactive
+	}
+
+	delete(f.active, id)
+	return nil
+}
+
+func (f *fakeRecorder) Active() []string {
+	ret := make([]string, 0)
+	for id := range f.active {
+		ret = append(ret, id)
+	}
+
+	return ret
+}
+
+func TestRecordingAPI(t *testing.T) {
+	r := &fakeRecorder{
+		active: make(map[string]bool),
+	}
+	fe := New(&Config{APIToken: "secret", Recorder: r}, &fakeManager{})
+	mux := http.NewServeMux()
+	fe.registerAPI(mux)
+	fe.registerRecordings(mux)
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		auth         string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Test #1: unauthorized",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/recording",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "Test #2: start",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/recording",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Test #3: start active recording",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/a-b%40BE/recording",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "Test #4: list",
+			method:       http.MethodGet,
+			path:         "/api/v1/recordings",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"probers": ["a-b@BE"]}`,
+		},
+		{
+			name:         "Test #5: stop",
+			method:       http.MethodDelete,
+			path:         "/api/v1/probers/a-b%40BE/recording",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusNoContent,
+		},
+		{
+			name:         "Test #6: unknown prober",
+			method:       http.MethodPost,
+			path:         "/api/v1/probers/x@BE/recording",
+			auth:         "Bearer secret",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		req.Header.Set("Authorization", test.auth)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		if test.expectedBody != "" {
+			assert.JSONEq(t, test.expectedBody, rec.Body.String(), test.name)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package frontend
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/correlation"
+	"github.com/exaring/matroschka-prober/pkg/health"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+)
+
+const (
+	defaultMeasurementsLast = 10
+)
+
+// HealthTracker provides the health state of probers
+type HealthTracker interface {
+	State(id string) health.State
+}
+
+// ProberInspector provides read access to the state of probers
+type ProberInspector interface {
+	Status() []manager.ProberStatus
+	Measurements(id string, n int) ([]prober.TimedMeasurement, error)
+}
+
+type proberStatus struct {
+	ID          string      `json:"id"`
+	Path        string      `json:"path"`
+	Class       string      `json:"class"`
+	TOS         uint8       `json:"tos"`
+	State       string      `json:"state"`
+	Health      string      `json:"health"`
+	Hops        []hopStatus `json:"hops"`
+	SrcAddrs    addrRange   `json:"src_addrs"`
+	LocalAddr   string      `json:"local_addr,omitempty"`
+	UDPPort     uint16      `json:"udp_port"`
+	PPS         uint64      `json:"pps"`
+	ProbesSent  uint64      `json:"probes_sent"`
+	ProbesRecv  uint64      `json:"probes_received"`
+	LatePackets uint64      `json:"late_packets"`
+	Suspects    *suspects   `json:"suspects,omitempty"`
+}
+
+// suspects are the correlation scores of the routers and segments along the path of a prober
+type suspects struct {
+	Routers  []correlation.Suspect `json:"routers"`
+	Segments []correlation.Suspect `json:"segments"`
+}
+
+type hopStatus struct {
+	Name     string    `json:"name"`
+	DstAddrs addrRange `json:"dst_addrs"`
+	SrcAddrs addrRange `json:"src_addrs"`
+}
+
+// addrRange summarizes the addresses generated from a range as they can be too many to list
+type addrRange struct {
+	First string `json:"first,omitempty"`
+	Last  string `json:"last,omitempty"`
+	Count int    `json:"count"`
+}
+
+type measurementsResponse struct {
+	ID           string              `json:"id"`
+	Measurements []measurementStatus `json:"measurements"`
+}
+
+type measurementStatus struct {
+	Timestamp time.Time `json:"timestamp"`
+	Sent      uint64    `json:"sent"`
+	Received  uint64    `json:"received"`
+	Loss      float64   `json:"loss_ratio"`
+	RTTMin    uint64    `json:"rtt_min_ns"`
+	RTTAvg    uint64    `json:"rtt_avg_ns"`
+	RTTMax    uint64    `json:"rtt_max_ns"`
+	RTTP50    uint64    `json:"rtt_p50_ns"`
+	RTTP90    uint64    `json:"rtt_p90_ns"`
+	RTTP99    uint64    `json:"rtt_p99_ns"`
+	Jitter    uint64    `json:"jitter_ns"`
+}
+
+func (fe *Frontend) inspector() ProberInspector {
+	i, ok := fe.proberReg.(ProberInspector)
+	if !ok {
+		return nil
+	}
+
+	return i
+}
+
+func (fe *Frontend) handleListProbers(w http.ResponseWriter, r *http.Request) {
+	i := fe.inspector()
+	if i == nil {
+		writeError(w, http.StatusNotImplemented, "Prober status is not supported")
+		return
+	}
+
+	var results map[string]correlation.Result
+	if fe.cfg.Suspects != nil {
+		results = make(map[string]correlation.Result)
+		for _, res := range fe.cfg.Suspects.Correlate() {
+			results[res.Class] = res
+		}
+	}
+
+	status := i.Status()
+	ret := make([]proberStatus, 0, len(status))
+	for _, s := range status {
+		ps := newProberStatus(s, fe.healthState(s))
+		if res, ok := results[s.Class]; ok {
+			ps.Suspects = newSuspects(&res, s)
+		}
+
+		ret = append(ret, ps)
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (fe *Frontend) handleMeasurements(w http.ResponseWriter, r *http.Request, id string) {
+	i := fe.inspector()
+	if i == nil {
+		writeError(w, http.StatusNotImplemented, "Prober status is not supported")
+		return
+	}
+
+	last := defaultMeasurementsLast
+	if v := r.URL.Query().Get("last"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "Parameter last must be a positive integer")
+			return
+		}
+		last = n
+	}
+
+	measurements, err := i.Measurements(id, last)
+	if err != nil {
+		writeManagerError(w, err)
+		return
+	}
+
+	ret := measurementsResponse{
+		ID:           id,
+		Measurements: make([]measurementStatus, 0, len(measurements)),
+	}
+	for _, m := range measurements {
+		ret.Measurements = append(ret.Measurements, newMeasurementStatus(m))
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func newMeasurementStatus(m prober.TimedMeasurement) measurementStatus {
+	return measurementStatus{
+		Timestamp: time.Unix(0, m.Ts).UTC(),
+		Sent:      m.Sent,
+		Received:  m.Received,
+		Loss:      m.Loss(),
+		RTTMin:    m.RTTMin,
+		RTTAvg:    m.RTTAvg(),
+		RTTMax:    m.RTTMax,
+		RTTP50:    m.RTTQuantile(0.5),
+		RTTP90:    m.RTTQuantile(0.9),
+		RTTP99:    m.RTTQuantile(0.99),
+		Jitter:    m.Jitter(),
+	}
+}
+
+func newProberStatus(s manager.ProberStatus, h health.State) proberStatus {
+	ret := proberStatus{
+		ID:          s.ID,
+		Path:        s.Path,
+		Class:       s.Class,
+		TOS:         s.Config.TOS.Value,
+		State:       proberState(s),
+		Health:      h.String(),
+		Hops:        make([]hopStatus, 0, len(s.Config.Hops)),
+		SrcAddrs:    newAddrRange(s.Config.SrcAddrs),
+		UDPPort:     s.UDPPort,
+		PPS:         s.Config.PPS,
+		ProbesSent:  s.ProbesSent,
+		ProbesRecv:  s.ProbesReceived,
+		LatePackets: s.LatePackets,
+	}
+
+	if s.LocalAddr != nil {
+		ret.LocalAddr = s.LocalAddr.String()
+	}
+
+	for _, h := range s.Config.Hops {
+		ret.Hops = append(ret.Hops, hopStatus{
+			Name:     h.Name,
+			DstAddrs: newAddrRange(h.DstRange),
+			SrcAddrs: newAddrRange(h.SrcRange),
+		})
+	}
+
+	return ret
+}
+
+func newSuspects(res *correlation.Result, s manager.ProberStatus) *suspects {
+	hops := make([]string, len(s.Config.Hops))
+	for i := range s.Config.Hops {
+		hops[i] = s.Config.Hops[i].Name
+	}
+
+	ret := &suspects{}
+	ret.Routers, ret.Segments = res.PathSuspects(hops)
+	return ret
+}
+
+func proberState(s manager.ProberStatus) string {
+	switch {
+	case s.Paused:
+		return "paused"
+	case s.Running:
+		return "running"
+	default:
+		return "stopped"
+	}
+}
+
+func newAddrRange(addrs []net.IP) addrRange {
+	if len(addrs) == 0 {
+		return addrRange{}
+	}
+
+	return addrRange{
+		First: addrs[0].String(),
+		Last:  addrs[len(addrs)-1].String(),
+		Count: len(addrs),
+	}
+}
@@ -0,0 +1,171 @@
+package frontend
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/correlation"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+func (f *fakeManager) Status() []manager.ProberStatus {
+	return []manager.ProberStatus{
+		{
+			ID:    "a-b@BE",
+			Path:  "a-b",
+			Class: "BE",
+			Config: prober.Config{
+				TOS: prober.TOS{
+					Name: "BE",
+				},
+				PPS:      25,
+				SrcAddrs: []net.IP{{10, 0, 0, 1}},
+				Hops: []prober.Hop{
+					{
+						Name:     "a",
+						DstRange: []net.IP{{10, 1, 0, 0}, {10, 1, 0, 1}},
+						SrcRange: []net.IP{{10, 2, 0, 0}},
+					},
+				},
+			},
+			Status: prober.Status{
+				Running:    true,
+				LocalAddr:  net.IP{192, 0, 2, 1},
+				UDPPort:    32768,
+				ProbesSent: 100,
+			},
+		},
+	}
+}
+
+func (f *fakeManager) Measurements(id string, n int) ([]prober.TimedMeasurement, error) {
+	if !f.probes[id] {
+		return nil, manager.ErrNotFound
+	}
+
+	ret := []prober.TimedMeasurement{
+		{
+			Ts: 1000000000,
+			Measurement: &measurement.Measurement{
+				Sent:     4,
+				Received: 3,
+				RTTSum:   600,
+				RTTMin:   100,
+				RTTMax:   300,
+				RTTs:     []uint64{300, 100, 200},
+			},
+		},
+		{
+			Ts: 2000000000,
+			Measurement: &measurement.Measurement{
+				Sent: 4,
+			},
+		},
+	}
+
+	if n < len(ret) {
+		ret = ret[len(ret)-n:]
+	}
+
+	return ret, nil
+}
+
+func TestStatusAPI(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		suspects     SuspectFinder
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "Test #1: list probers",
+			path:         "/api/v1/probers",
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"id": "a-b@BE", "path": "a-b", "class": "BE", "tos": 0, "state": "running", "health": "unknown",
+				"hops": [{"name": "a", "dst_addrs": {"first": "10.1.0.0", "last": "10.1.0.1", "count": 2},
+				"src_addrs": {"first": "10.2.0.0", "last": "10.2.0.0", "count": 1}}],
+				"src_addrs": {"first": "10.0.0.1", "last": "10.0.0.1", "count": 1}, "local_addr": "192.0.2.1", "udp_port": 32768, "pps": 25,
+				"probes_sent": 100, "probes_received": 0, "late_packets": 0}]`,
+		},
+		{
+			name: "Test #2: list probers with suspects",
+			path: "/api/v1/probers",
+			suspects: fakeSuspects{
+				{
+					Class:   "BE",
+					Failing: []string{"a-b@BE"},
+					Routers: []correlation.Suspect{
+						{Name: "a", Score: 1, FailingPaths: 1},
+						{Name: "x", HealthyPaths: 1},
+					},
+					Segments: []correlation.Suspect{
+						{Name: "a>prober", From: "a", To: "prober", Score: 1, FailingPaths: 1},
+						{Name: "prober>a", From: "prober", To: "a", Score: 1, FailingPaths: 1},
+						{Name: "prober>x", From: "prober", To: "x", HealthyPaths: 1},
+					},
+				},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: `[{"id": "a-b@BE", "path": "a-b", "class": "BE", "tos": 0, "state": "running", "health": "unknown",
+				"hops": [{"name": "a", "dst_addrs": {"first": "10.1.0.0", "last": "10.1.0.1", "count": 2},
+				"src_addrs": {"first": "10.2.0.0", "last": "10.2.0.0", "count": 1}}],
+				"src_addrs": {"first": "10.0.0.1", "last": "10.0.0.1", "count": 1}, "local_addr": "192.0.2.1", "udp_port": 32768, "pps": 25,
+				"probes_sent": 100, "probes_received": 0, "late_packets": 0,
+				"suspects": {"routers": [{"name": "a", "score": 1, "failing_paths": 1, "healthy_paths": 0}],
+				"segments": [{"name": "a>prober", "from": "a", "to": "prober", "score": 1, "failing_paths": 1, "healthy_paths": 0},
+				{"name": "prober>a", "from": "prober", "to": "a", "score": 1, "failing_paths": 1, "healthy_paths": 0}]}}]`,
+		},
+		{
+			name:         "Test #3: last measurement",
+			path:         "/api/v1/probers/a-b%40BE/measurements?last=1",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id": "a-b@BE", "measurements": [{"timestamp": "1970-01-01T00:00:02Z", "sent": 4, "received": 0,
+				"loss_ratio": 1, "rtt_min_ns": 0, "rtt_avg_ns": 0, "rtt_max_ns": 0, "rtt_p50_ns": 0, "rtt_p90_ns": 0, "rtt_p99_ns": 0, "jitter_ns": 0}]}`,
+		},
+		{
+			name:         "Test #4: default number of measurements",
+			path:         "/api/v1/probers/a-b%40BE/measurements",
+			expectedCode: http.StatusOK,
+			expectedBody: `{"id": "a-b@BE", "measurements": [{"timestamp": "1970-01-01T00:00:01Z", "sent": 4, "received": 3,
+				"loss_ratio": 0.25, "rtt_min_ns": 100, "rtt_avg_ns": 200, "rtt_max_ns": 300, "rtt_p50_ns": 200, "rtt_p90_ns": 300, "rtt_p99_ns": 300, "jitter_ns": 150},
+				{"timestamp": "1970-01-01T00:00:02Z", "sent": 4, "received": 0,
+				"loss_ratio": 1, "rtt_min_ns": 0, "rtt_avg_ns": 0, "rtt_max_ns": 0, "rtt_p50_ns": 0, "rtt_p90_ns": 0, "rtt_p99_ns": 0, "jitter_ns": 0}]}`,
+		},
+		{
+			name:         "Test #5: invalid last",
+			path:         "/api/v1/probers/a-b%40BE/measurements?last=0",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "Test #6: unknown prober",
+			path:         "/api/v1/probers/x%40BE/measurements",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		m := &fakeManager{
+			probes: map[string]bool{
+				"a-b@BE": true,
+			},
+		}
+		fe := New(&Config{Suspects: test.suspects}, m)
+		mux := http.NewServeMux()
+		fe.registerAPI(mux)
+
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		assert.Equal(t, test.expectedCode, rec.Code, test.name)
+		if test.expectedBody != "" {
+			assert.JSONEq(t, test.expectedBody, rec.Body.String(), test.name)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package frontend
+
+import (
+	"net/http"
+
+	"github.com/exaring/matroschka-prober/pkg/correlation"
+)
+
+const (
+	apiSuspectsPath = "/api/v1/suspects"
+)
+
+// SuspectFinder correlates failing paths to find the routers and segments likely causing the failures
+type SuspectFinder interface {
+	Correlate() []correlation.Result
+}
+
+type suspectsResponse struct {
+	Results []correlation.Result `json:"results"`
+}
+
+func (fe *Frontend) registerSuspects(mux *http.ServeMux) {
+	mux.HandleFunc(apiSuspectsPath, fe.handleSuspectsRequest)
+}
+
+// handleSuspectsRequest returns the suspects of all classes or of the class given by the class parameter
+func (fe *Frontend) handleSuspectsRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if fe.cfg.Suspects == nil {
+		writeError(w, http.StatusNotImplemented, "Correlation is not supported")
+		return
+	}
+
+	class := r.URL.Query().Get("class")
+	ret := suspectsResponse{
+		Results: make([]correlation.Result, 0),
+	}
+
+	for _, res := range fe.cfg.Suspects.Correlate() {
+		if class != "" && res.Class != class {
+			continue
+		}
+
+		ret.Results = append(ret.Results, res)
+	}
+
+	writeJSON(w, http.StatusOK, ret)
+}
@@ -0,0 +1,35 @@
+package frontend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/correlation"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSuspects []correlation.Result
+
+func (f fakeSuspects) Correlate() []correlation.Result {
+	return f
+}
+
+func TestSuspectsRequest(t *testing.T) {
+	fe := New(&Config{
+		Suspects: fakeSuspects{
+			{Class: "BE", Failing: []string{"a-x@BE"}, Routers: []correlation.Suspect{{Name: "x", Score: 1, FailingPaths: 1}}},
+			{Class: "EF", Failing: []string{}},
+		},
+	}, &fakeManager{})
+	mux := http.NewServeMux()
+	fe.registerSuspects(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/suspects?class=BE", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"results": [{"class": "BE", "failing": ["a-x@BE"], "segments": null,
+		"routers": [{"name": "x", "score": 1, "failing_paths": 1, "healthy_paths": 0}]}]}`, rec.Body.String())
+}
@@ -0,0 +1,249 @@
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	metricPrefix = "matroschka_"
+)
+
+// State is the health state of a path
+type State int
+
+const (
+	// StateUnknown is the state of paths without finished measurements
+	StateUnknown State = iota
+	// StateUp is the state of paths within all thresholds
+	StateUp
+	// StateDegraded is the state of paths exceeding a degraded threshold
+	StateDegraded
+	// StateDown is the state of paths exceeding the down threshold
+	StateDown
+)
+
+var states = []State{StateUnknown, StateUp, StateDegraded, StateDown}
+
+func (s State) String() string {
+	switch s {
+	case StateUp:
+		return "up"
+	case StateDegraded:
+		return "degraded"
+	case StateDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// Config is the configuration of the health tracker
+type Config struct {
+	DegradedLossPercent float64
+	DownLossPercent     float64
+	DegradedRTTP99MS    *float64 // Not checked if nil
+	Hysteresis          float64  // Ratio values must drop below a threshold to recover
+	Consecutive         uint64   // Buckets a new state must persist before it is taken
+}
+
+// Transition is a change of the health state of a prober
+type Transition struct {
+	Prober *prober.Prober
+	Ts     int64 // Start of the bucket that caused the transition
+	From   State
+	To     State
+}
+
+// Listener gets notified about state transitions
+type Listener interface {
+	StateChanged(t Transition)
+}
+
+// Tracker derives the health state of probers from their finished measurements
+type Tracker struct {
+	cfg       Config
+	paths     map[string]*path
+	listeners []Listener
+	l         sync.RWMutex
+}
+
+type path struct {
+	state       State
+	candidate   State
+	count       uint64
+	changes     uint64
+	labelKeys   []string
+	labelValues []string
+}
+
+// New creates a new health tracker
+func New(cfg Config) *Tracker {
+	return &Tracker{
+		cfg:   cfg,
+		paths: make(map[string]*path),
+	}
+}
+
+// Update replaces the configuration. States are kept.
+func (t *Tracker) Update(cfg Config) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	t.cfg = cfg
+}
+
+// AddListener registers a listener for state transitions. It must be called before probers are started.
+func (t *Tracker) AddListener(l Listener) {
+	t.listeners = append(t.listeners, l)
+}
+
+// State returns the current state of a prober
+func (t *Tracker) State(id string) State {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	p, ok := t.paths[id]
+	if !ok {
+		return StateUnknown
+	}
+
+	return p.state
+}
+
+// Forget drops the state of a removed prober
+func (t *Tracker) Forget(id string) {
+	t.l.Lock()
+	defer t.l.Unlock()
+
+	delete(t.paths, id)
+}
+
+// Observe derives the state of a prober from a finished measurement
+func (t *Tracker) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	if m.Sent == 0 {
+		return
+	}
+
+	t.l.Lock()
+	ps := t.paths[p.ID()]
+	if ps == nil {
+		keys, values := p.MetricLabels()
+		ps = &path{
+			labelKeys:   keys,
+			labelValues: values,
+		}
+		t.paths[p.ID()] = ps
+	}
+
+	from := ps.state
+	changed := ps.update(&t.cfg, t.cfg.next(ps.state, m))
+	to := ps.state
+	t.l.Unlock()
+
+	if !changed {
+		return
+	}
+
+	log.Infof("Path %q class %q changed state from %s to %s", p.PathName(), p.TOS().Name, from, to)
+	for _, l := range t.listeners {
+		l.StateChanged(Transition{
+			Prober: p,
+			Ts:     ts,
+			From:   from,
+			To:     to,
+		})
+	}
+}
+
+// update moves towards the candidate state and returns true if the state changed
+func (p *path) update(cfg *Config, candidate State) bool {
+	if candidate == p.state {
+		p.count = 0
+		return false
+	}
+
+	if candidate != p.candidate {
+		p.candidate = candidate
+		p.count = 0
+	}
+	p.count++
+
+	// The first state is taken immediately
+	if p.state != StateUnknown && p.count < cfg.Consecutive {
+		return false
+	}
+
+	p.state = candidate
+	p.count = 0
+	p.changes++
+	return true
+}
+
+// next returns the state indicated by a measurement. Improving requires the values to drop below
+// the thresholds reduced by the hysteresis.
+func (c *Config) next(current State, m *measurement.Measurement) State {
+	s := c.classify(m, 1)
+	if s >= current || current == StateUnknown {
+		return s
+	}
+
+	lenient := c.classify(m, 1-c.Hysteresis)
+	if lenient < current {
+		return lenient
+	}
+
+	return current
+}
+
+// classify returns the state of a measurement with all thresholds scaled by factor
+func (c *Config) classify(m *measurement.Measurement, factor float64) State {
+	lossPercent := m.Loss() * 100
+	if lossPercent >= c.DownLossPercent*factor {
+		return StateDown
+	}
+
+	if lossPercent >= c.DegradedLossPercent*factor {
+		return StateDegraded
+	}
+
+	if c.DegradedRTTP99MS != nil {
+		p99 := float64(m.RTTQuantile(0.99)) / float64(time.Millisecond)
+		if p99 >= *c.DegradedRTTP99MS*factor {
+			return StateDegraded
+		}
+	}
+
+	return StateUp
+}
+
+// Describe is required by prometheus interface
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect collects the health states and sends them to prometheus
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	for _, p := range t.paths {
+		stateDesc := prometheus.NewDesc(metricPrefix+"path_state", "Health state of a path. 1 for the current state, 0 otherwise.", append(append([]string{}, p.labelKeys...), "state"), nil)
+		changesDesc := prometheus.NewDesc(metricPrefix+"path_state_changes_total", "Health state changes of a path", p.labelKeys, nil)
+
+		for _, s := range states {
+			v := float64(0)
+			if s == p.state {
+				v = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, v, append(append([]string{}, p.labelValues...), s.String())...)
+		}
+
+		ch <- prometheus.MustNewConstMetric(changesDesc, prometheus.CounterValue, float64(p.changes), p.labelValues...)
+	}
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func float64ptr(f float64) *float64 {
+	return &f
+}
+
+func TestTransitions(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		losses   []uint64 // Lost probes out of 100 per bucket
+		rtt      uint64
+		expected []State
+	}{
+		{
+			name: "Test #1: immediate transitions",
+			cfg: Config{
+				DegradedLossPercent: 1,
+				DownLossPercent:     50,
+				Consecutive:         1,
+			},
+			losses:   []uint64{0, 1, 50, 100, 10, 0},
+			expected: []State{StateUp, StateDegraded, StateDown, StateDown, StateDegraded, StateUp},
+		},
+		{
+			name: "Test #2: consecutive buckets",
+			cfg: Config{
+				DegradedLossPercent: 1,
+				DownLossPercent:     50,
+				Consecutive:         2,
+			},
+			losses:   []uint64{100, 0, 100, 0, 0, 5, 60, 5, 5},
+			expected: []State{StateDown, StateDown, StateDown, StateDown, StateUp, StateUp, StateUp, StateUp, StateDegraded},
+		},
+		{
+			name: "Test #3: hysteresis",
+			cfg: Config{
+				DegradedLossPercent: 10,
+				DownLossPercent:     50,
+				Hysteresis:          0.5,
+				Consecutive:         1,
+			},
+			losses:   []uint64{0, 10, 6, 5, 4, 50, 30, 24},
+			expected: []State{StateUp, StateDegraded, StateDegraded, StateDegraded, StateUp, StateDown, StateDown, StateDegraded},
+		},
+		{
+			name: "Test #4: RTT threshold",
+			cfg: Config{
+				DegradedLossPercent: 1,
+				DownLossPercent:     50,
+				DegradedRTTP99MS:    float64ptr(10),
+				Consecutive:         1,
+			},
+			losses:   []uint64{0},
+			rtt:      uint64(20 * time.Millisecond),
+			expected: []State{StateDegraded},
+		},
+	}
+
+	for _, test := range tests {
+		p := &path{}
+		for i, lost := range test.losses {
+			m := &measurement.Measurement{
+				Sent:     100,
+				Received: 100 - lost,
+				RTTs:     []uint64{test.rtt},
+			}
+
+			p.update(&test.cfg, test.cfg.next(p.state, m))
+			assert.Equalf(t, test.expected[i], p.state, "%s: bucket %d", test.name, i)
+		}
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := New(Config{
+		DegradedLossPercent: 1,
+		DownLossPercent:     50,
+		Consecutive:         1,
+	})
+
+	p, err := prober.New(prober.Config{
+		PathName: "a-b",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		Hops: []prober.Hop{
+			{Name: "a"},
+			{Name: "b"},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, StateUnknown, tr.State("a-b@BE"))
+	tr.Observe(p, 0, &measurement.Measurement{Sent: 100, Received: 100})
+	tr.Observe(p, 1, &measurement.Measurement{Sent: 100, Received: 0})
+	assert.Equal(t, StateDown, tr.State("a-b@BE"))
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(tr)
+	expected := `
+# HELP matroschka_path_state Health state of a path. 1 for the current state, 0 otherwise.
+# TYPE matroschka_path_state gauge
+matroschka_path_state{path="a-b",state="degraded",tos="BE"} 0
+matroschka_path_state{path="a-b",state="down",tos="BE"} 1
+matroschka_path_state{path="a-b",state="unknown",tos="BE"} 0
+matroschka_path_state{path="a-b",state="up",tos="BE"} 0
+# HELP matroschka_path_state_changes_total Health state changes of a path
+# TYPE matroschka_path_state_changes_total counter
+matroschka_path_state_changes_total{path="a-b",tos="BE"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+
+	tr.Forget("a-b@BE")
+	assert.Equal(t, StateUnknown, tr.State("a-b@BE"))
+}
@@ -0,0 +1,141 @@
+package influx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/sink"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize  = 10000
+	retryDelay = time.Second
+)
+
+// Config is the configuration of the InfluxDB sink. Exactly one of URL and UDPAddress must be set.
+type Config struct {
+	URL           string
+	UDPAddress    string
+	Token         string
+	Measurement   string
+	BatchSize     int
+	FlushInterval time.Duration
+	Timeout       time.Duration
+	Retries       int // Retries of failed HTTP writes
+}
+
+// Sink writes every finished bucket as a point in line protocol to InfluxDB.
+// Points are timestamped with the start of their bucket.
+type Sink struct {
+	cfg    Config
+	writer writer
+	queue  chan []byte
+}
+
+// New creates a new InfluxDB sink
+func New(cfg Config) (*Sink, error) {
+	s := &Sink{
+		cfg:   cfg,
+		queue: make(chan []byte, queueSize),
+	}
+
+	if cfg.UDPAddress != "" {
+		w, err := newUDPWriter(cfg.UDPAddress)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create UDP writer: %v", err)
+		}
+
+		s.writer = w
+		return s, nil
+	}
+
+	s.writer = &httpWriter{
+		url:     cfg.URL,
+		token:   cfg.Token,
+		timeout: cfg.Timeout,
+		client:  &http.Client{},
+	}
+
+	return s, nil
+}
+
+// Write queues a finished bucket without blocking the prober
+func (s *Sink) Write(r *sink.Result) {
+	select {
+	case s.queue <- line(s.cfg.Measurement, r):
+	default:
+		log.Errorf("InfluxDB queue is full. Dropping point of prober %q", r.Prober)
+	}
+}
+
+// Run writes queued points in batches until ctx is cancelled. Remaining points are written before returning.
+func (s *Sink) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]byte, 0)
+	lines := 0
+	flush := func(ctx context.Context) {
+		if lines == 0 {
+			return
+		}
+
+		s.write(ctx, batch, lines)
+		batch = batch[:0]
+		lines = 0
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain(&batch, &lines)
+			flush(context.Background())
+			s.writer.close()
+			return
+		case <-ticker.C:
+			flush(ctx)
+		case l := <-s.queue:
+			batch = append(batch, l...)
+			lines++
+			if lines >= s.cfg.BatchSize {
+				flush(ctx)
+			}
+		}
+	}
+}
+
+// drain moves all queued points into the batch
+func (s *Sink) drain(batch *[]byte, lines *int) {
+	for {
+		select {
+		case l := <-s.queue:
+			*batch = append(*batch, l...)
+			*lines++
+		default:
+			return
+		}
+	}
+}
+
+func (s *Sink) write(ctx context.Context, batch []byte, lines int) {
+	attempts := s.cfg.Retries + 1
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retryDelay):
+			}
+		}
+
+		err := s.writer.write(ctx, batch)
+		if err == nil {
+			return
+		}
+
+		log.Errorf("Unable to write %d points to InfluxDB (attempt %d/%d): %v", lines, i+1, attempts, err)
+	}
+}
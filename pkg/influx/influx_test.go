@@ -0,0 +1,155 @@
+package influx
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/exaring/matroschka-prober/pkg/sink"
+	"github.com/stretchr/testify/assert"
+)
+
+func newResult(labels []prober.Label, ts int64, m *measurement.Measurement) *sink.Result {
+	return &sink.Result{
+		Prober:      "a-b@BE",
+		Path:        "a-b",
+		Hops:        "a-b b",
+		Class:       "BE",
+		Labels:      labels,
+		Ts:          ts,
+		Measurement: m,
+	}
+}
+
+func TestLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   []prober.Label
+		m        *measurement.Measurement
+		expected string
+	}{
+		{
+			name: "Test #1: escaping and empty tags",
+			labels: []prober.Label{
+				{Key: "site", Value: "fra,01"},
+				{Key: "customer", Value: ""},
+			},
+			m: &measurement.Measurement{
+				Sent:     4,
+				Received: 3,
+				RTTMin:   1000,
+				RTTMax:   3000,
+				RTTSum:   6000,
+				RTTs:     []uint64{3000, 1000, 2000},
+			},
+			expected: "my\\ measurement,path=a-b\\ b,site=fra\\,01,tos=BE sent=4i,received=3i,rtt_min=1000i,rtt_avg=2000i,rtt_max=3000i,rtt_p50=2000i,rtt_p90=3000i,rtt_p99=3000i 1000000000\n",
+		},
+		{
+			name: "Test #2: no RTT fields without received packets",
+			m: &measurement.Measurement{
+				Sent: 4,
+			},
+			expected: "my\\ measurement,path=a-b\\ b,tos=BE sent=4i,received=0i 1000000000\n",
+		},
+	}
+
+	for _, test := range tests {
+		r := newResult(test.labels, int64(time.Second), test.m)
+		assert.Equal(t, test.expected, string(line("my measurement", r)), test.name)
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	var l sync.Mutex
+	bodies := make([]string, 0)
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		defer l.Unlock()
+
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s, err := New(Config{
+		URL:           srv.URL + "/api/v2/write?org=o&bucket=b",
+		Token:         "secret",
+		Measurement:   "matroschka",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Timeout:       time.Second,
+		Retries:       1,
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		s.Write(newResult(nil, int64(i), &measurement.Measurement{Sent: 1}))
+	}
+
+	assert.Eventually(t, func() bool {
+		l.Lock()
+		defer l.Unlock()
+		return len(bodies) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+
+	expected := []string{
+		"matroschka,path=a-b\\ b,tos=BE sent=1i,received=0i 0\nmatroschka,path=a-b\\ b,tos=BE sent=1i,received=0i 1\n",
+		"matroschka,path=a-b\\ b,tos=BE sent=1i,received=0i 2\n",
+	}
+	assert.Equal(t, expected, bodies)
+}
+
+func TestUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	w, err := newUDPWriter(conn.LocalAddr().String())
+	assert.NoError(t, err)
+	defer w.close()
+
+	l := strings.Repeat("x", 599) + "\n"
+	batch := []byte(strings.Repeat(l, 5))
+	assert.NoError(t, w.write(context.Background(), batch))
+
+	sizes := make([]int, 0)
+	buf := make([]byte, 2*maxDatagramSize)
+	for i := 0; i < 3; i++ {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.NoError(t, err) {
+			break
+		}
+
+		sizes = append(sizes, n)
+	}
+
+	assert.Equal(t, []int{1200, 1200, 600}, sizes)
+}
@@ -0,0 +1,85 @@
+package influx
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/exaring/matroschka-prober/pkg/sink"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	tagEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+type tag struct {
+	key   string
+	value string
+}
+
+type field struct {
+	key   string
+	value uint64
+}
+
+// line encodes a finished bucket as a point in line protocol. Tags with empty values are omitted
+// as InfluxDB does not accept them.
+func line(name string, r *sink.Result) []byte {
+	tags := make([]tag, 0, len(r.Labels)+2)
+	for _, l := range r.Labels {
+		if l.Value == "" {
+			continue
+		}
+
+		tags = append(tags, tag{key: l.Key, value: l.Value})
+	}
+	tags = append(tags, tag{key: "tos", value: r.Class}, tag{key: "path", value: r.Hops})
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].key < tags[j].key
+	})
+
+	m := r.Measurement
+	fields := []field{
+		{key: "sent", value: m.Sent},
+		{key: "received", value: m.Received},
+	}
+
+	if m.Received > 0 {
+		fields = append(fields,
+			field{key: "rtt_min", value: m.RTTMin},
+			field{key: "rtt_avg", value: m.RTTAvg()},
+			field{key: "rtt_max", value: m.RTTMax},
+			field{key: "rtt_p50", value: m.RTTQuantile(0.5)},
+			field{key: "rtt_p90", value: m.RTTQuantile(0.9)},
+			field{key: "rtt_p99", value: m.RTTQuantile(0.99)},
+		)
+	}
+
+	b := make([]byte, 0, 256)
+	b = append(b, measurementEscaper.Replace(name)...)
+	for _, t := range tags {
+		b = append(b, ',')
+		b = append(b, tagEscaper.Replace(t.key)...)
+		b = append(b, '=')
+		b = append(b, tagEscaper.Replace(t.value)...)
+	}
+
+	for i, f := range fields {
+		if i == 0 {
+			b = append(b, ' ')
+		} else {
+			b = append(b, ',')
+		}
+
+		b = append(b, f.key...)
+		b = append(b, '=')
+		b = strconv.AppendUint(b, f.value, 10)
+		b = append(b, 'i')
+	}
+
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, r.Ts, 10)
+	return append(b, '\n')
+}
@@ -0,0 +1,112 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxDatagramSize keeps UDP datagrams below the common MTU to avoid fragmentation
+	maxDatagramSize = 1400
+)
+
+// writer writes a batch of lines
+type writer interface {
+	write(ctx context.Context, batch []byte) error
+	close() error
+}
+
+type httpWriter struct {
+	url     string
+	token   string
+	timeout time.Duration
+	client  *http.Client
+}
+
+func (w *httpWriter) write(ctx context.Context, batch []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Token "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("InfluxDB returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (w *httpWriter) close() error {
+	return nil
+}
+
+type udpWriter struct {
+	conn net.Conn
+}
+
+func newUDPWriter(addr string) (*udpWriter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to dial: %v", err)
+	}
+
+	return &udpWriter{
+		conn: conn,
+	}, nil
+}
+
+// write sends the batch split at line boundaries into datagrams of up to maxDatagramSize bytes
+func (w *udpWriter) write(ctx context.Context, batch []byte) error {
+	for len(batch) > 0 {
+		n := datagramLength(batch)
+		_, err := w.conn.Write(batch[:n])
+		if err != nil {
+			return err
+		}
+
+		batch = batch[n:]
+	}
+
+	return nil
+}
+
+// datagramLength returns the length of the complete lines fitting into a datagram. A line longer than
+// a datagram is sent on its own.
+func datagramLength(batch []byte) int {
+	if len(batch) <= maxDatagramSize {
+		return len(batch)
+	}
+
+	i := bytes.LastIndexByte(batch[:maxDatagramSize], '\n')
+	if i >= 0 {
+		return i + 1
+	}
+
+	i = bytes.IndexByte(batch, '\n')
+	if i < 0 {
+		return len(batch)
+	}
+
+	return i + 1
+}
+
+func (w *udpWriter) close() error {
+	return w.conn.Close()
+}
@@ -0,0 +1,127 @@
+package perprober
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLister []string
+
+func (f fakeLister) IDs() []string {
+	return f
+}
+
+type fakeEncoder struct {
+	items  []int
+	closed bool
+	l      sync.Mutex
+}
+
+func (e *fakeEncoder) Encode(v int) error {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	e.items = append(e.items, v)
+	return nil
+}
+
+func (e *fakeEncoder) Flush() error {
+	return nil
+}
+
+func (e *fakeEncoder) Close() error {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	e.closed = true
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name           string
+		limits         Limits
+		stop           bool
+		expectedItems  []int
+		expectedReason string
+	}{
+		{
+			name:           "Test #1: queued items are written on stop",
+			stop:           true,
+			expectedItems:  []int{1, 2, 3},
+			expectedReason: "stopped",
+		},
+		{
+			name:           "Test #2: item limit",
+			limits:         Limits{Items: 2},
+			expectedItems:  []int{1, 2},
+			expectedReason: "limit reached",
+		},
+	}
+
+	for _, test := range tests {
+		enc := &fakeEncoder{}
+		w := NewWriter[int]("test", enc, test.limits)
+		for i := 1; i <= 3; i++ {
+			assert.NoError(t, w.Write(i), test.name)
+		}
+
+		go w.Run()
+		if test.stop {
+			w.Stop()
+		}
+		<-w.done
+
+		written, reason := w.Written()
+		assert.Equal(t, test.expectedItems, enc.items, test.name)
+		assert.Equal(t, uint64(len(test.expectedItems)), written, test.name)
+		assert.Equal(t, test.expectedReason, reason, test.name)
+		assert.True(t, enc.closed, test.name)
+	}
+}
+
+func TestWriterDuration(t *testing.T) {
+	enc := &fakeEncoder{}
+	w := NewWriter[int]("test", enc, Limits{Duration: 10 * time.Millisecond})
+	w.Run()
+
+	_, reason := w.Written()
+	assert.Equal(t, "duration reached", reason)
+	assert.True(t, enc.closed)
+}
+
+func TestSet(t *testing.T) {
+	s := NewSet[int, *fakeEncoder]("test", fakeLister{"a@BE", "b@BE"})
+	open := func() (*fakeEncoder, error) {
+		return &fakeEncoder{}, nil
+	}
+
+	_, err := s.Start("x@BE", Limits{}, open)
+	assert.Equal(t, ErrNotFound, err, "Test #1: unknown prober")
+	assert.Equal(t, ErrInactive, s.Stop("a@BE"), "Test #2: stop inactive writer")
+	assert.Equal(t, ErrInactive, s.Write("a@BE", 1), "Test #3: write to inactive writer")
+
+	a, err := s.Start("a@BE", Limits{}, open)
+	assert.NoError(t, err)
+	_, err = s.Start("a@BE", Limits{}, open)
+	assert.Equal(t, ErrActive, err, "Test #4: start active writer")
+
+	b, err := s.Start("b@BE", Limits{Items: 1}, open)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a@BE", "b@BE"}, s.IDs())
+	assert.Equal(t, []*fakeEncoder{a, b}, s.Encoders())
+
+	assert.NoError(t, s.Write("a@BE", 1))
+	assert.NoError(t, s.Write("b@BE", 2))
+	assert.Eventually(t, func() bool {
+		return !s.Active("b@BE")
+	}, time.Second, 10*time.Millisecond, "Test #5: writer is removed once its limit is reached")
+
+	s.Forget("a@BE")
+	assert.Empty(t, s.IDs())
+	assert.Equal(t, []int{1}, a.items, "Test #6: queued items are written when a prober is forgotten")
+	assert.True(t, a.closed)
+}
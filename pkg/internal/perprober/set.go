@@ -0,0 +1,193 @@
+// Package perprober writes items of selected probers to one file per prober. Writers are started
+// and stopped at runtime, e.g. through the API.
+package perprober
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotFound is returned if a prober does not exist
+	ErrNotFound = errors.New("Prober not found")
+
+	// ErrActive is returned when starting a writer that is already active
+	ErrActive = errors.New("Already active for this prober")
+
+	// ErrInactive is returned when stopping a writer that is not active
+	ErrInactive = errors.New("Not active for this prober")
+
+	unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._@-]`)
+)
+
+// ProberLister lists the IDs of all probers
+type ProberLister interface {
+	IDs() []string
+}
+
+// Set holds the active writers of all probers
+type Set[T any, E Encoder[T]] struct {
+	name    string
+	probers ProberLister
+	active  map[string]*active[T, E]
+	l       sync.RWMutex
+}
+
+type active[T any, E Encoder[T]] struct {
+	enc E
+	w   *Writer[T]
+}
+
+// NewSet creates a new set. name describes what is written in logs, e.g. "recording".
+func NewSet[T any, E Encoder[T]](name string, probers ProberLister) *Set[T, E] {
+	return &Set[T, E]{
+		name:    name,
+		probers: probers,
+		active:  make(map[string]*active[T, E]),
+	}
+}
+
+// FileName returns a file name for a prober ID that is safe to use in paths
+func FileName(id string) string {
+	return unsafeChars.ReplaceAllString(id, "_")
+}
+
+// Start starts writing the items of a prober to the encoder returned by open
+func (s *Set[T, E]) Start(id string, limits Limits, open func() (E, error)) (E, error) {
+	var enc E
+	if !s.exists(id) {
+		return enc, ErrNotFound
+	}
+
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if _, ok := s.active[id]; ok {
+		return enc, ErrActive
+	}
+
+	enc, err := open()
+	if err != nil {
+		return enc, err
+	}
+
+	a := &active[T, E]{
+		enc: enc,
+		w:   NewWriter[T](fmt.Sprintf("%s of prober %q", s.name, id), enc, limits),
+	}
+	s.active[id] = a
+	go s.run(id, a)
+
+	return enc, nil
+}
+
+func (s *Set[T, E]) exists(id string) bool {
+	for _, i := range s.probers.IDs() {
+		if i == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run runs the writer and removes it once it ended by itself
+func (s *Set[T, E]) run(id string, a *active[T, E]) {
+	a.w.Run()
+
+	s.l.Lock()
+	if s.active[id] == a {
+		delete(s.active, id)
+	}
+	s.l.Unlock()
+
+	written, reason := a.w.Written()
+	log.Infof("Finished %s of prober %q (%s, %d written)", s.name, id, reason, written)
+}
+
+// Stop stops writing the items of a prober. It blocks until all queued items are written.
+func (s *Set[T, E]) Stop(id string) error {
+	s.l.Lock()
+	a, ok := s.active[id]
+	delete(s.active, id)
+	s.l.Unlock()
+
+	if !ok {
+		return ErrInactive
+	}
+
+	a.w.Stop()
+	return nil
+}
+
+// Forget stops the writer of a removed prober
+func (s *Set[T, E]) Forget(id string) {
+	s.Stop(id)
+}
+
+// Close stops all writers
+func (s *Set[T, E]) Close() {
+	for _, id := range s.IDs() {
+		s.Stop(id)
+	}
+}
+
+// IDs returns the sorted IDs of all probers with an active writer
+func (s *Set[T, E]) IDs() []string {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	ret := make([]string, 0, len(s.active))
+	for id := range s.active {
+		ret = append(ret, id)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
+
+// Encoders returns the encoders of all active writers sorted by prober ID
+func (s *Set[T, E]) Encoders() []E {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	ids := make([]string, 0, len(s.active))
+	for id := range s.active {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	ret := make([]E, 0, len(ids))
+	for _, id := range ids {
+		ret = append(ret, s.active[id].enc)
+	}
+
+	return ret
+}
+
+// Active returns whether a writer of a prober is active
+func (s *Set[T, E]) Active(id string) bool {
+	s.l.RLock()
+	defer s.l.RUnlock()
+
+	_, ok := s.active[id]
+	return ok
+}
+
+// Write queues an item if the writer of its prober is active
+func (s *Set[T, E]) Write(id string, v T) error {
+	s.l.RLock()
+	a, ok := s.active[id]
+	s.l.RUnlock()
+
+	if !ok {
+		return ErrInactive
+	}
+
+	return a.w.Write(v)
+}
@@ -0,0 +1,146 @@
+package perprober
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	queueSize = 10000
+)
+
+// ErrQueueFull is returned if an item is dropped because the queue of a writer is full
+var ErrQueueFull = errors.New("Queue is full")
+
+// Encoder writes items to a file
+type Encoder[T any] interface {
+	Encode(v T) error
+	Flush() error
+	Close() error
+}
+
+// Limits end a writer by themselves. Zero values are unlimited.
+type Limits struct {
+	Duration time.Duration
+	Items    uint64
+}
+
+// Writer passes queued items to an encoder in its own goroutine, so producers never block
+type Writer[T any] struct {
+	name    string
+	enc     Encoder[T]
+	limits  Limits
+	queue   chan T
+	stop    chan struct{}
+	stopped sync.Once
+	done    chan struct{}
+	written uint64
+	reason  string
+}
+
+// NewWriter creates a new writer. name describes the written file in logs.
+func NewWriter[T any](name string, enc Encoder[T], limits Limits) *Writer[T] {
+	return &Writer[T]{
+		name:   name,
+		enc:    enc,
+		limits: limits,
+		queue:  make(chan T, queueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// Write queues an item without blocking
+func (w *Writer[T]) Write(v T) error {
+	select {
+	case w.queue <- v:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run writes queued items until the writer is stopped or one of its limits is reached.
+// The encoder is flushed whenever the queue is empty and closed at the end.
+func (w *Writer[T]) Run() {
+	defer close(w.done)
+
+	var timeout <-chan time.Time
+	if w.limits.Duration > 0 {
+		t := time.NewTimer(w.limits.Duration)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	for !w.full() {
+		select {
+		case <-w.stop:
+			w.drain()
+			w.finish("stopped")
+			return
+		case <-timeout:
+			w.finish("duration reached")
+			return
+		case v := <-w.queue:
+			w.encode(v)
+			w.drain()
+			err := w.enc.Flush()
+			if err != nil {
+				log.Errorf("Unable to flush %s: %v", w.name, err)
+			}
+		}
+	}
+
+	w.finish("limit reached")
+}
+
+// Stop stops the writer. It blocks until all queued items are written.
+func (w *Writer[T]) Stop() {
+	w.stopped.Do(func() {
+		close(w.stop)
+	})
+	<-w.done
+}
+
+// Written returns the number of written items and why the writer ended. It must only be called after Run returned.
+func (w *Writer[T]) Written() (uint64, string) {
+	return w.written, w.reason
+}
+
+func (w *Writer[T]) full() bool {
+	return w.limits.Items > 0 && w.written >= w.limits.Items
+}
+
+// drain writes queued items up to the limit
+func (w *Writer[T]) drain() {
+	for !w.full() {
+		select {
+		case v := <-w.queue:
+			w.encode(v)
+		default:
+			return
+		}
+	}
+}
+
+func (w *Writer[T]) encode(v T) {
+	err := w.enc.Encode(v)
+	if err != nil {
+		log.Errorf("Unable to write %s: %v", w.name, err)
+		return
+	}
+
+	w.written++
+}
+
+func (w *Writer[T]) finish(reason string) {
+	w.reason = reason
+
+	err := w.enc.Close()
+	if err != nil {
+		log.Errorf("Unable to close %s: %v", w.name, err)
+	}
+}
@@ -0,0 +1,493 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrNotFound is returned if a prober does not exist
+	ErrNotFound = errors.New("Prober not found")
+
+	// ErrExists is returned when adding a prober that already exists
+	ErrExists = errors.New("Prober already exists")
+)
+
+// StartError is returned if probers could not be started. Changes were applied anyway.
+type StartError struct {
+	Errors []error
+}
+
+func (e *StartError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	msgs := make([]string, len(e.Errors))
+	for i := range e.Errors {
+		msgs[i] = e.Errors[i].Error()
+	}
+
+	return fmt.Sprintf("%d errors: %s", len(e.Errors), strings.Join(msgs, "; "))
+}
+
+// Forgetter is implemented by observers that keep state per prober. Forget is called when a prober is removed.
+type Forgetter interface {
+	Forget(id string)
+}
+
+// Manager owns all probers and allows to change them at runtime
+type Manager struct {
+	ctx        context.Context
+	cfg        *config.Config
+	probers    map[string]*entry
+	observers  []prober.Observer
+	recorder   prober.ProbeRecorder
+	capturer   prober.PacketCapturer
+	collectors []prometheus.Collector
+	l          sync.RWMutex
+	applyLock  sync.Mutex
+}
+
+type entry struct {
+	spec   config.ProberSpec
+	prober *prober.Prober
+	paused bool
+}
+
+// New creates a new manager. Probers are stopped when ctx is cancelled.
+func New(ctx context.Context) *Manager {
+	return &Manager{
+		ctx:     ctx,
+		probers: make(map[string]*entry),
+	}
+}
+
+// AddObserver registers an observer that is added to all probers started afterwards
+func (m *Manager) AddObserver(o prober.Observer) {
+	m.observers = append(m.observers, o)
+}
+
+// SetRecorder sets the probe recorder of all probers started afterwards.
+// Forget is called on the recorder as well if it implements Forgetter.
+func (m *Manager) SetRecorder(r prober.ProbeRecorder) {
+	m.recorder = r
+}
+
+// SetCapturer sets the packet capturer of all probers started afterwards.
+// Forget is called on the capturer as well if it implements Forgetter.
+func (m *Manager) SetCapturer(c prober.PacketCapturer) {
+	m.capturer = c
+}
+
+// AddCollector registers an additional collector that is returned by GetCollectors
+func (m *Manager) AddCollector(c prometheus.Collector) {
+	m.collectors = append(m.collectors, c)
+}
+
+// GetCollectors returns the collectors of all probers and additional collectors
+func (m *Manager) GetCollectors() []prometheus.Collector {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	ret := make([]prometheus.Collector, 0, len(m.probers)+len(m.collectors))
+	for _, e := range m.probers {
+		ret = append(ret, e.prober)
+	}
+
+	return append(ret, m.collectors...)
+}
+
+// IDs returns the sorted IDs of all probers
+func (m *Manager) IDs() []string {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	ret := make([]string, 0, len(m.probers))
+	for id := range m.probers {
+		ret = append(ret, id)
+	}
+	sort.Strings(ret)
+
+	return ret
+}
+
+// ProberStatus describes a prober and its runtime state
+type ProberStatus struct {
+	ID     string
+	Path   string
+	Class  string
+	Paused bool
+	Config prober.Config
+	prober.Status
+}
+
+// Status returns the status of all probers sorted by ID
+func (m *Manager) Status() []ProberStatus {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	ret := make([]ProberStatus, 0, len(m.probers))
+	for id, e := range m.probers {
+		ret = append(ret, ProberStatus{
+			ID:     id,
+			Path:   e.spec.Path,
+			Class:  e.spec.Class,
+			Paused: e.paused,
+			Config: e.spec.Config,
+			Status: e.prober.Status(),
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ID < ret[j].ID
+	})
+
+	return ret
+}
+
+// Measurements returns up to n of the most recent finished measurements of a prober, oldest first
+func (m *Manager) Measurements(id string, n int) ([]prober.TimedMeasurement, error) {
+	m.l.RLock()
+	e, ok := m.probers[id]
+	m.l.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return e.prober.LastMeasurements(n), nil
+}
+
+// Routers returns the routers of the current config
+func (m *Manager) Routers() []config.Router {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	if m.cfg == nil {
+		return nil
+	}
+
+	return append([]config.Router{}, m.cfg.Routers...)
+}
+
+// Reload applies a new config: Probers that are not part of cfg anymore or have changed are stopped and
+// new ones are started. Unchanged probers keep running (or stay paused) and keep their measurements.
+// Probers added at runtime are removed unless they are part of cfg.
+// Nothing is changed if any of the new probers can not be created. Otherwise cfg becomes the current config
+// and probers that fail to start are reported by a StartError. They are left out and retried with the next reload.
+func (m *Manager) Reload(cfg *config.Config) error {
+	specs, err := cfg.ProberSpecs()
+	if err != nil {
+		return fmt.Errorf("Unable to get prober specs: %v", err)
+	}
+
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	return m.apply(cfg, specs)
+}
+
+func (m *Manager) apply(cfg *config.Config, specs []config.ProberSpec) error {
+	wanted := make(map[string]config.ProberSpec, len(specs))
+	for _, s := range specs {
+		wanted[s.Key()] = s
+	}
+
+	unchanged := make(map[string]bool)
+	m.l.RLock()
+	for id, e := range m.probers {
+		s, ok := wanted[id]
+		if ok && reflect.DeepEqual(s.Config, e.spec.Config) {
+			unchanged[id] = true
+			delete(wanted, id)
+		}
+	}
+	m.l.RUnlock()
+
+	created := make([]*entry, 0, len(wanted))
+	for _, s := range specs {
+		if _, ok := wanted[s.Key()]; !ok {
+			continue
+		}
+
+		p, err := m.newProber(s)
+		if err != nil {
+			return err
+		}
+
+		created = append(created, &entry{
+			spec:   s,
+			prober: p,
+		})
+	}
+
+	paused := make(map[string]bool)
+	removed := make([]*prober.Prober, 0)
+	m.l.Lock()
+	m.cfg = cfg
+	for id, e := range m.probers {
+		if unchanged[id] {
+			continue
+		}
+
+		log.Infof("Stopping prober %q", id)
+		paused[id] = e.paused
+		removed = append(removed, e.prober)
+		delete(m.probers, id)
+	}
+	m.l.Unlock()
+	stopProbers(removed)
+
+	for _, p := range removed {
+		m.forget(p.ID())
+	}
+
+	errs := make([]error, 0)
+	for _, e := range created {
+		e.paused = paused[e.spec.Key()]
+		err := m.start(e)
+		if err != nil {
+			log.Errorf("%v", err)
+			errs = append(errs, err)
+		}
+	}
+
+	return startError(errs)
+}
+
+// AddPath adds probers for a path that is not part of the config and returns the IDs of the started ones
+func (m *Manager) AddPath(p config.Path) ([]string, error) {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	m.l.RLock()
+	cfg := m.cfg
+	m.l.RUnlock()
+
+	if cfg == nil {
+		return nil, fmt.Errorf("No config loaded")
+	}
+
+	specs, err := cfg.NewPathProberSpecs(p)
+	if err != nil {
+		return nil, err
+	}
+
+	m.l.RLock()
+	for _, s := range specs {
+		if _, ok := m.probers[s.Key()]; ok {
+			m.l.RUnlock()
+			return nil, fmt.Errorf("%w: %s", ErrExists, s.Key())
+		}
+	}
+	m.l.RUnlock()
+
+	created := make([]*entry, 0, len(specs))
+	for _, s := range specs {
+		pr, err := m.newProber(s)
+		if err != nil {
+			return nil, err
+		}
+
+		created = append(created, &entry{
+			spec:   s,
+			prober: pr,
+		})
+	}
+
+	ids := make([]string, 0, len(created))
+	errs := make([]error, 0)
+	for _, e := range created {
+		err := m.start(e)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		ids = append(ids, e.spec.Key())
+	}
+
+	return ids, startError(errs)
+}
+
+// newProber creates a prober with all observers attached
+func (m *Manager) newProber(s config.ProberSpec) (*prober.Prober, error) {
+	p, err := prober.New(s.Config)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get new prober for %q: %v", s.Key(), err)
+	}
+
+	for _, o := range m.observers {
+		p.AddObserver(o)
+	}
+
+	if m.recorder != nil {
+		p.SetRecorder(m.recorder)
+	}
+
+	if m.capturer != nil {
+		p.SetCapturer(m.capturer)
+	}
+
+	return p, nil
+}
+
+// start starts the prober of an entry unless it is paused and adds the entry
+func (m *Manager) start(e *entry) error {
+	if !e.paused {
+		log.Infof("Starting prober for path %q class %q", e.spec.Path, e.spec.Class)
+		err := e.prober.Start(m.ctx)
+		if err != nil {
+			return fmt.Errorf("Unable to start prober for %q: %v", e.spec.Key(), err)
+		}
+	}
+
+	m.l.Lock()
+	m.probers[e.spec.Key()] = e
+	m.l.Unlock()
+
+	return nil
+}
+
+// startError returns a StartError for errs or nil if there are none
+func startError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &StartError{
+		Errors: errs,
+	}
+}
+
+// Remove stops and removes a prober
+func (m *Manager) Remove(id string) error {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	m.l.Lock()
+	e, ok := m.probers[id]
+	if !ok {
+		m.l.Unlock()
+		return ErrNotFound
+	}
+	delete(m.probers, id)
+	m.l.Unlock()
+
+	log.Infof("Removing prober %q", id)
+	e.prober.Stop()
+	m.forget(id)
+	return nil
+}
+
+func (m *Manager) forget(id string) {
+	for _, o := range m.observers {
+		if f, ok := o.(Forgetter); ok {
+			f.Forget(id)
+		}
+	}
+
+	if f, ok := m.recorder.(Forgetter); ok {
+		f.Forget(id)
+	}
+
+	if f, ok := m.capturer.(Forgetter); ok {
+		f.Forget(id)
+	}
+}
+
+// Pause stops a prober but keeps it and its measurements
+func (m *Manager) Pause(id string) error {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	e, err := m.get(id, false)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Pausing prober %q", id)
+	m.l.Lock()
+	e.paused = true
+	m.l.Unlock()
+
+	e.prober.Stop()
+	return nil
+}
+
+// Resume restarts a paused prober
+func (m *Manager) Resume(id string) error {
+	m.applyLock.Lock()
+	defer m.applyLock.Unlock()
+
+	e, err := m.get(id, true)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Resuming prober %q", id)
+	err = e.prober.Start(m.ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to start prober %q: %v", id, err)
+	}
+
+	m.l.Lock()
+	e.paused = false
+	m.l.Unlock()
+
+	return nil
+}
+
+// get returns the entry of a prober that is in the given paused state
+func (m *Manager) get(id string, paused bool) (*entry, error) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+
+	e, ok := m.probers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if e.paused != paused {
+		return nil, fmt.Errorf("Prober %q is already in the requested state", id)
+	}
+
+	return e, nil
+}
+
+// Stop stops all probers
+func (m *Manager) Stop() {
+	m.l.RLock()
+	probers := make([]*prober.Prober, 0, len(m.probers))
+	for _, e := range m.probers {
+		probers = append(probers, e.prober)
+	}
+	m.l.RUnlock()
+
+	stopProbers(probers)
+}
+
+// stopProbers stops probers in parallel and waits for them to finish
+func stopProbers(probers []*prober.Prober) {
+	wg := sync.WaitGroup{}
+	for _, p := range probers {
+		wg.Add(1)
+		go func(p *prober.Prober) {
+			defer wg.Done()
+			p.Stop()
+		}(p)
+	}
+
+	wg.Wait()
+}
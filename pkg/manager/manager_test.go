@@ -0,0 +1,110 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConfig(paths ...string) *config.Config {
+	timeout := uint64(50)
+	cfg := &config.Config{
+		Defaults: &config.Defaults{
+			TimeoutMS: &timeout,
+		},
+		Routers: []config.Router{
+			{Name: "lo", DstRange: "127.0.0.1/32", SrcRange: "127.0.0.1/32"},
+		},
+	}
+
+	for _, p := range paths {
+		cfg.Paths = append(cfg.Paths, config.Path{
+			Name: p,
+			Hops: []string{"lo"},
+		})
+	}
+
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func TestManager(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Raw sockets require root")
+	}
+
+	m := New(context.Background())
+	defer m.Stop()
+
+	assert.NoError(t, m.Reload(testConfig("a", "b")))
+	assert.Equal(t, []string{"a@BE", "b@BE"}, m.IDs())
+	a := m.probers["a@BE"].prober
+
+	assert.NoError(t, m.Pause("a@BE"))
+	assert.False(t, a.Running())
+	assert.Error(t, m.Pause("a@BE"), "pausing a paused prober must fail")
+	assert.ErrorIs(t, m.Pause("x@BE"), ErrNotFound)
+
+	ids, err := m.AddPath(config.Path{Name: "c", Hops: []string{"lo"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"c@BE"}, ids)
+	_, err = m.AddPath(config.Path{Name: "c", Hops: []string{"lo"}})
+	assert.ErrorIs(t, err, ErrExists)
+	_, err = m.AddPath(config.Path{Name: "d", Hops: []string{"unknown"}})
+	assert.Error(t, err)
+
+	assert.NoError(t, m.Remove("b@BE"))
+	assert.Equal(t, []string{"a@BE", "c@BE"}, m.IDs())
+
+	// Unchanged probers are kept (and stay paused), runtime additions are dropped
+	assert.NoError(t, m.Reload(testConfig("a", "b")))
+	assert.Equal(t, []string{"a@BE", "b@BE"}, m.IDs())
+	assert.Same(t, a, m.probers["a@BE"].prober)
+	assert.False(t, a.Running())
+
+	assert.NoError(t, m.Resume("a@BE"))
+	assert.True(t, a.Running())
+}
+
+func TestReloadStartError(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Raw sockets require root")
+	}
+
+	m := New(context.Background())
+	defer m.Stop()
+
+	assert.NoError(t, m.Reload(testConfig("a")))
+	a := m.probers["a@BE"].prober
+
+	// A prober that fails to start does not keep the others from starting
+	cfg := testConfig("a", "b", "c")
+	vrf := "does-not-exist0"
+	cfg.Paths[1].VRF = &vrf
+	err := m.Reload(cfg)
+	var serr *StartError
+	if assert.ErrorAs(t, err, &serr) {
+		assert.Len(t, serr.Errors, 1)
+		assert.Contains(t, err.Error(), "b@BE")
+	}
+	assert.Equal(t, []string{"a@BE", "c@BE"}, m.IDs())
+	assert.Same(t, a, m.probers["a@BE"].prober)
+	assert.True(t, m.probers["c@BE"].prober.Running())
+	assert.Same(t, cfg, m.cfg)
+
+	// Invalid configs do not change anything
+	prev := cfg
+	cfg = testConfig("a")
+	srcIf := "does-not-exist0"
+	cfg.Paths[0].SrcInterface = &srcIf
+	err = m.Reload(cfg)
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &serr), "nothing was applied")
+	assert.Equal(t, []string{"a@BE", "c@BE"}, m.IDs())
+	assert.True(t, a.Running())
+	assert.Same(t, prev, m.cfg)
+}
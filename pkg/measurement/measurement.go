@@ -1,6 +1,8 @@
 package measurement
 
 import (
+	"math"
+	"sort"
 	"sync"
 	"time"
 
@@ -94,3 +96,59 @@ func (m *MeasurementsDB) Get(ts int64) *Measurement {
 	ret := *m.m[ts]
 	return &ret
 }
+
+// Loss returns the ratio of probes that did not return
+func (m *Measurement) Loss() float64 {
+	if m.Sent == 0 || m.Received >= m.Sent {
+		return 0
+	}
+
+	return float64(m.Sent-m.Received) / float64(m.Sent)
+}
+
+// RTTAvg returns the average RTT of all returned probes
+func (m *Measurement) RTTAvg() uint64 {
+	if m.Received == 0 {
+		return 0
+	}
+
+	return m.RTTSum / m.Received
+}
+
+// RTTQuantile returns the q-quantile (0 <= q <= 1) of the RTTs using the nearest rank method
+func (m *Measurement) RTTQuantile(q float64) uint64 {
+	if len(m.RTTs) == 0 {
+		return 0
+	}
+
+	rtts := make([]uint64, len(m.RTTs))
+	copy(rtts, m.RTTs)
+	sort.Slice(rtts, func(i, j int) bool {
+		return rtts[i] < rtts[j]
+	})
+
+	rank := int(math.Ceil(q * float64(len(rtts))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return rtts[rank-1]
+}
+
+// Jitter returns the mean absolute difference between the RTTs of consecutively received probes
+func (m *Measurement) Jitter() uint64 {
+	if len(m.RTTs) < 2 {
+		return 0
+	}
+
+	sum := uint64(0)
+	for i := 1; i < len(m.RTTs); i++ {
+		if m.RTTs[i] > m.RTTs[i-1] {
+			sum += m.RTTs[i] - m.RTTs[i-1]
+		} else {
+			sum += m.RTTs[i-1] - m.RTTs[i]
+		}
+	}
+
+	return sum / uint64(len(m.RTTs)-1)
+}
@@ -0,0 +1,101 @@
+package prober
+
+import (
+	"net"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	log "github.com/sirupsen/logrus"
+)
+
+// CapturedPacket is a probe packet as it was sent or received
+type CapturedPacket struct {
+	Seq      uint64
+	Ts       int64
+	Received bool   // False for sent packets
+	Data     []byte // IPv4 packet. It is only valid during the call to Capture.
+}
+
+// PacketCapturer gets the packets of probers it is capturing. Capture must not block the prober.
+type PacketCapturer interface {
+	Capturing(p *Prober) bool
+	Capture(p *Prober, pkt CapturedPacket)
+}
+
+// SetCapturer sets the packet capturer. It must be called before the prober is started.
+func (p *Prober) SetCapturer(c PacketCapturer) {
+	p.capturer = c
+}
+
+func (p *Prober) capturing() bool {
+	return p.capturer != nil && p.capturer.Capturing(p)
+}
+
+// captureSent captures a crafted packet including the outer IPv4 header added when sending it
+func (p *Prober) captureSent(pr *probe, payload []byte, src net.IP, dst net.IP) {
+	ip := &layers.IPv4{
+		SrcIP:    src,
+		DstIP:    dst,
+		Version:  4,
+		Protocol: layers.IPProtocolGRE,
+		TOS:      p.cfg.TOS.Value,
+		TTL:      ttl,
+	}
+
+	p.capture(pr.Seq, pr.Ts, false, ip, gopacket.Payload(payload))
+}
+
+// captureReceived captures a returned packet. The UDP socket only returns the payload,
+// so the IPv4 and UDP headers are reconstructed from the socket metadata.
+func (p *Prober) captureReceived(pr *probe, ts int64, payload []byte, meta packetMeta) {
+	p.l.Lock()
+	localAddr := p.localAddr
+	p.l.Unlock()
+
+	ip := &layers.IPv4{
+		DstIP:    localAddr,
+		Version:  4,
+		Protocol: layers.IPProtocolUDP,
+	}
+
+	udp := &layers.UDP{
+		DstPort: layers.UDPPort(p.dstUDPPort),
+	}
+
+	if meta.src != nil {
+		ip.SrcIP = meta.src.IP
+		udp.SrcPort = layers.UDPPort(meta.src.Port)
+	}
+
+	if meta.tos >= 0 {
+		ip.TOS = uint8(meta.tos)
+	}
+
+	if meta.ttl >= 0 {
+		ip.TTL = uint8(meta.ttl)
+	}
+
+	udp.SetNetworkLayerForChecksum(ip)
+	p.capture(pr.Seq, ts, true, ip, udp, gopacket.Payload(payload))
+}
+
+func (p *Prober) capture(seq uint64, ts int64, received bool, l ...gopacket.SerializableLayer) {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+
+	err := gopacket.SerializeLayers(buf, opts, l...)
+	if err != nil {
+		log.Errorf("Unable to serialize captured packet: %v", err)
+		return
+	}
+
+	p.capturer.Capture(p, CapturedPacket{
+		Seq:      seq,
+		Ts:       ts,
+		Received: received,
+		Data:     buf.Bytes(),
+	})
+}
@@ -0,0 +1,77 @@
+package prober
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCapturer struct {
+	pkts []CapturedPacket
+}
+
+func (f *fakeCapturer) Capturing(p *Prober) bool {
+	return true
+}
+
+func (f *fakeCapturer) Capture(p *Prober, pkt CapturedPacket) {
+	f.pkts = append(f.pkts, pkt)
+}
+
+func TestCapture(t *testing.T) {
+	p, err := New(Config{
+		SrcAddrs: []net.IP{net.IPv4(169, 254, 0, 1)},
+		Hops: []Hop{
+			{
+				Name:     "a",
+				DstRange: []net.IP{net.IPv4(10, 0, 0, 1)},
+				SrcRange: []net.IP{net.IPv4(10, 0, 0, 2)},
+			},
+		},
+		TOS: TOS{Name: "EF", Value: 0xb8},
+	})
+	assert.NoError(t, err)
+
+	c := &fakeCapturer{}
+	p.SetCapturer(c)
+	p.localAddr = net.IPv4(169, 254, 0, 1)
+	p.dstUDPPort = 32768
+	assert.True(t, p.capturing())
+
+	pr := &probe{Seq: 7, Ts: 100}
+	pkt, err := p.craftPacket(pr)
+	assert.NoError(t, err)
+	p.captureSent(pr, pkt, net.IPv4(169, 254, 0, 1), net.IPv4(10, 0, 0, 1))
+
+	payload, err := pr.marshal()
+	assert.NoError(t, err)
+	p.captureReceived(pr, 150, payload, packetMeta{
+		tos: 0xb8,
+		ttl: 62,
+		src: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 32768},
+	})
+
+	if !assert.Len(t, c.pkts, 2) {
+		return
+	}
+
+	sent := gopacket.NewPacket(c.pkts[0].Data, layers.LayerTypeIPv4, gopacket.Default)
+	assert.Equal(t, uint64(7), c.pkts[0].Seq)
+	assert.False(t, c.pkts[0].Received)
+	assert.Equal(t, "10.0.0.1", sent.NetworkLayer().(*layers.IPv4).DstIP.String())
+	assert.Equal(t, layers.IPProtocolGRE, sent.NetworkLayer().(*layers.IPv4).Protocol)
+	assert.Equal(t, pkt, sent.NetworkLayer().LayerPayload())
+
+	recv := gopacket.NewPacket(c.pkts[1].Data, layers.LayerTypeIPv4, gopacket.Default)
+	assert.Equal(t, int64(150), c.pkts[1].Ts)
+	assert.True(t, c.pkts[1].Received)
+	ip := recv.NetworkLayer().(*layers.IPv4)
+	assert.Equal(t, "10.0.0.2", ip.SrcIP.String())
+	assert.Equal(t, uint8(0xb8), ip.TOS)
+	assert.Equal(t, uint8(62), ip.TTL)
+	assert.Equal(t, layers.UDPPort(32768), recv.TransportLayer().(*layers.UDP).DstPort)
+	assert.Equal(t, payload, recv.ApplicationLayer().Payload())
+}
@@ -1,7 +1,6 @@
 package prober
 
 import (
-	"strings"
 	"sync/atomic"
 	"time"
 
@@ -33,6 +32,7 @@ func (p *Prober) Collect(ch chan<- prometheus.Metric) {
 	p.collectRTTMax(ch, m)
 	p.collectRTTAvg(ch, m)
 	p.collectLatePackets(ch, m)
+	p.collectTOSMismatches(ch)
 }
 
 func (p *Prober) labels() []string {
@@ -53,7 +53,7 @@ func (p *Prober) labelValues() []string {
 	}
 
 	values[len(values)-2] = p.cfg.TOS.Name
-	values[len(values)-1] = strings.Join(p.getHopNames(), "-")
+	values[len(values)-1] = p.Path()
 	return values
 }
 
@@ -102,6 +102,12 @@ func (p *Prober) collectLatePackets(ch chan<- prometheus.Metric, m *measurement.
 	ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(n), p.labelValues()...)
 }
 
+func (p *Prober) collectTOSMismatches(ch chan<- prometheus.Metric) {
+	desc := prometheus.NewDesc(metricPrefix+"tos_mismatches_total", "Received packets with a DSCP other than the configured one", p.labels(), nil)
+	n := atomic.LoadUint64(&p.tosMismatches)
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(n), p.labelValues()...)
+}
+
 func (p *Prober) lastFinishedMeasurement() int64 {
 	measurementLengthNS := int64(p.cfg.MeasurementLengthMS) * int64(time.Millisecond)
 	timeoutNS := int64(p.cfg.TimeoutMS) * int64(time.Millisecond)
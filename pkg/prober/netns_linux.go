@@ -0,0 +1,68 @@
+//go:build linux
+
+package prober
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	netnsDir = "/var/run/netns"
+)
+
+// WithNetNS runs f inside the named network namespace (as created by `ip netns add`).
+// Sockets created by f remain in that namespace. An empty name runs f in the current namespace.
+func WithNetNS(name string, f func() error) error {
+	if name == "" {
+		return f()
+	}
+
+	// setns only affects the current thread. So we must not be moved to another one.
+	runtime.LockOSThread()
+	restored := false
+	defer func() {
+		// A thread we failed to move back must not be reused. It is terminated by the runtime
+		// if the goroutine exits without unlocking it.
+		if restored {
+			runtime.UnlockOSThread()
+		}
+	}()
+
+	orig, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", unix.Gettid()))
+	if err != nil {
+		restored = true
+		return fmt.Errorf("Unable to open current network namespace: %v", err)
+	}
+	defer orig.Close()
+
+	target, err := os.Open(filepath.Join(netnsDir, name))
+	if err != nil {
+		restored = true
+		return fmt.Errorf("Unable to open network namespace %q: %v", name, err)
+	}
+	defer target.Close()
+
+	err = unix.Setns(int(target.Fd()), unix.CLONE_NEWNET)
+	if err != nil {
+		restored = true
+		return fmt.Errorf("Unable to enter network namespace %q: %v", name, err)
+	}
+
+	defer func() {
+		err := unix.Setns(int(orig.Fd()), unix.CLONE_NEWNET)
+		if err != nil {
+			log.Errorf("Unable to leave network namespace %q: %v", name, err)
+			return
+		}
+
+		restored = true
+	}()
+
+	return f()
+}
@@ -0,0 +1,82 @@
+//go:build linux
+
+package prober
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestNetNS creates a throwaway network namespace. The test is skipped if that is not possible.
+func newTestNetNS(t *testing.T) string {
+	if os.Geteuid() != 0 {
+		t.Skip("Creating network namespaces requires root")
+	}
+
+	name := fmt.Sprintf("matroschka-test-%d", os.Getpid())
+	out, err := exec.Command("ip", "netns", "add", name).CombinedOutput()
+	if err != nil {
+		t.Skipf("Unable to create network namespace: %v: %s", err, out)
+	}
+
+	t.Cleanup(func() {
+		exec.Command("ip", "netns", "del", name).Run()
+	})
+
+	return name
+}
+
+func interfaceNames(t *testing.T) []string {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		t.Fatalf("Unable to list interfaces: %v", err)
+	}
+
+	ret := make([]string, len(ifs))
+	for i := range ifs {
+		ret[i] = ifs[i].Name
+	}
+
+	return ret
+}
+
+func TestWithNetNS(t *testing.T) {
+	name := newTestNetNS(t)
+	outside := interfaceNames(t)
+
+	var inside []string
+	var s *udpSockWrapper
+	err := WithNetNS(name, func() error {
+		inside = interfaceNames(t)
+
+		var err error
+		s, err = newUDPSockWrapper(32768, socketOptions{})
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"lo"}, inside)
+	assert.Equal(t, outside, interfaceNames(t), "namespace must be restored")
+	s.Close()
+
+	err = WithNetNS("matroschka-does-not-exist", func() error {
+		return nil
+	})
+	assert.Error(t, err)
+}
+
+func TestGetLocalAddrInNetNS(t *testing.T) {
+	name := newTestNetNS(t)
+
+	// The namespace has no routes (lo is down), so there is no local address
+	err := WithNetNS(name, func() error {
+		_, err := getLocalAddr(net.IPv4(192, 0, 2, 1), socketOptions{})
+		return err
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package prober
+
+import (
+	"fmt"
+)
+
+// WithNetNS runs f. Network namespaces are only supported on Linux.
+func WithNetNS(name string, f func() error) error {
+	if name == "" {
+		return f()
+	}
+
+	return fmt.Errorf("Network namespaces are only supported on Linux")
+}
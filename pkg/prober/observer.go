@@ -0,0 +1,72 @@
+package prober
+
+import (
+	"strings"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+)
+
+// Observer gets notified about every finished measurement of a prober
+type Observer interface {
+	Observe(p *Prober, ts int64, m *measurement.Measurement)
+}
+
+// AddObserver registers an observer. It must be called before the prober is started.
+func (p *Prober) AddObserver(o Observer) {
+	p.observers = append(p.observers, o)
+}
+
+// Config returns the configuration of the prober
+func (p *Prober) Config() Config {
+	return p.cfg
+}
+
+// ID returns the ID of the prober consisting of the path name and the class
+func (p *Prober) ID() string {
+	return p.cfg.PathName + "@" + p.cfg.TOS.Name
+}
+
+// PathName returns the name of the path in the config
+func (p *Prober) PathName() string {
+	return p.cfg.PathName
+}
+
+// Path returns the name of the probed path as used in the `path` label
+func (p *Prober) Path() string {
+	return strings.Join(p.getHopNames(), "-")
+}
+
+// MetricLabels returns the label names and values used for the metrics of the prober
+func (p *Prober) MetricLabels() ([]string, []string) {
+	return p.labels(), p.labelValues()
+}
+
+// TOS returns the type of service the prober is probing with
+func (p *Prober) TOS() TOS {
+	return p.cfg.TOS
+}
+
+// notifyObservers passes all measurements up to last that were not passed yet to the observers
+func (p *Prober) notifyObservers(last int64) {
+	if p.lastNotified == 0 {
+		p.lastNotified = last
+		return
+	}
+
+	measurementLengthNS := int64(p.cfg.MeasurementLengthMS) * int64(time.Millisecond)
+	for ts := p.lastNotified + measurementLengthNS; ts <= last; ts += measurementLengthNS {
+		m := p.measurements.Get(ts)
+		if m == nil {
+			continue
+		}
+
+		for _, o := range p.observers {
+			o.Observe(p, ts, m)
+		}
+	}
+
+	if last > p.lastNotified {
+		p.lastNotified = last
+	}
+}
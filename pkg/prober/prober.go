@@ -1,8 +1,10 @@
 package prober
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/exaring/matroschka-prober/pkg/measurement"
@@ -15,26 +17,41 @@ const (
 
 // Prober keeps the state of a prober instance. There is one instance per probed path.
 type Prober struct {
-	cfg            Config
-	dstUDPPort     uint16
-	localAddr      net.IP
-	clock          clock
-	mtu            uint16
-	payload        gopacket.Payload
-	probesReceived uint64
-	probesSent     uint64
-	rawConn        rawSocket // Used to send GRE packets
-	stop           chan struct{}
-	transitProbes  *transitProbes // Keeps track of in-flight packets
-	udpConn        udpSocket      // Used to receive returning packets
-	measurements   *measurement.MeasurementsDB
-	latePackets    uint64
+	cfg             Config
+	dstUDPPort      uint16
+	localAddr       net.IP
+	clock           clock
+	mtu             uint16
+	payload         gopacket.Payload
+	probesReceived  uint64
+	probesSent      uint64
+	rawConn         rawSocket      // Used to send GRE packets
+	transitProbes   *transitProbes // Keeps track of in-flight packets
+	udpConn         udpSocket      // Used to receive returning packets
+	measurements    *measurement.MeasurementsDB
+	latePackets     uint64
+	tosMismatches   uint64 // Returned probes with a DSCP other than the configured one
+	lastReceivedTOS uint32
+	observers       []Observer
+	recorder        ProbeRecorder
+	capturer        PacketCapturer
+	lastNotified    int64
+	lastSent        int64 // Timestamp of the last sent probe
+	cancel          context.CancelFunc
+	done            chan struct{} // Closed once a started prober has stopped completely
+	wg              sync.WaitGroup
+	l               sync.Mutex
 }
 
 // Config is the configuration of a prober
 type Config struct {
+	PathName            string // Name of the path in the config
 	BasePort            uint16
 	ConfiguredSrcAddr   net.IP
+	BindDevice          string // Device (e.g. a VRF) all sockets are bound to using SO_BINDTODEVICE
+	FwMark              uint32 // Mark set on sent probes using SO_MARK. 0 means no mark.
+	FwMarkReceive       bool   // Set FwMark on the receiving socket as well
+	NetNS               string // Name of the network namespace the sockets are created in
 	SrcAddrs            []net.IP
 	Hops                []Hop
 	StaticLabels        []Label
@@ -70,40 +87,96 @@ func New(c Config) (*Prober, error) {
 		mtu:           mtuMax,
 		transitProbes: newTransitProbes(),
 		measurements:  measurement.NewDB(),
-		stop:          make(chan struct{}),
 		payload:       make(gopacket.Payload, c.PayloadSizeBytes),
 	}
 
 	return pr, nil
 }
 
-// Start starts the prober
-func (p *Prober) Start() error {
+// Start starts the prober. The prober runs until ctx is cancelled or Stop is called.
+// A stopped prober can be started again and keeps its measurements.
+func (p *Prober) Start(ctx context.Context) error {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if p.running() {
+		return fmt.Errorf("Prober is already running")
+	}
+
 	err := p.init()
 	if err != nil {
 		return fmt.Errorf("Failed to init: %v", err)
 	}
 
-	go p.rttTimeoutChecker()
-	go p.sender()
-	go p.receiver()
-	go p.cleaner()
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	p.transitProbes = newTransitProbes()
+	senderDone := make(chan struct{})
+
+	p.wg.Add(4)
+	go p.rttTimeoutChecker(ctx)
+	go p.sender(ctx, senderDone)
+	go p.receiver(senderDone)
+	go p.cleaner(ctx)
+
+	go func(done chan struct{}) {
+		p.wg.Wait()
+		p.notifyObservers(p.alignTs(p.lastSent))
+		close(done)
+	}(p.done)
+
 	return nil
 }
 
-// Stop stops the prober
+// Stop stops the prober. It blocks until all in-flight probes returned or timed out
+// so the last measurement is complete.
 func (p *Prober) Stop() {
-	close(p.stop)
+	p.l.Lock()
+	cancel, done := p.cancel, p.done
+	p.l.Unlock()
+
+	if done == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
+
+// Running returns whether the prober is running
+func (p *Prober) Running() bool {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	return p.running()
 }
 
-func (p *Prober) cleaner() {
+func (p *Prober) running() bool {
+	if p.done == nil {
+		return false
+	}
+
+	select {
+	case <-p.done:
+		return false
+	default:
+		return true
+	}
+}
+
+func (p *Prober) cleaner(ctx context.Context) {
+	defer p.wg.Done()
+
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+
 	for {
 		select {
-		case <-p.stop:
+		case <-ctx.Done():
 			return
-		default:
-			time.Sleep(time.Second)
-			p.measurements.RemoveOlder(p.lastFinishedMeasurement())
+		case <-t.C:
+			p.notifyObservers(p.lastFinishedMeasurement())
+			p.measurements.RemoveOlder(p.oldestRetainedMeasurement())
 		}
 	}
 }
@@ -112,7 +185,22 @@ func (p *Prober) getSrcAddr(s uint64) net.IP {
 	return p.cfg.SrcAddrs[s%uint64(len(p.cfg.SrcAddrs))]
 }
 
+// init creates the sockets. Sockets opened before an error are closed again.
 func (p *Prober) init() error {
+	err := WithNetNS(p.cfg.NetNS, p.initSockets)
+	if err != nil {
+		p.closeSockets()
+		return err
+	}
+
+	return nil
+}
+
+func (p *Prober) initSockets() error {
+	// Drop the sockets of a previous run. They were closed when it stopped.
+	p.rawConn = nil
+	p.udpConn = nil
+
 	err := p.initRawSocket()
 	if err != nil {
 		return fmt.Errorf("Unable to initialize RAW socket: %v", err)
@@ -125,3 +213,15 @@ func (p *Prober) init() error {
 
 	return nil
 }
+
+func (p *Prober) closeSockets() {
+	if p.rawConn != nil {
+		p.rawConn.Close()
+		p.rawConn = nil
+	}
+
+	if p.udpConn != nil {
+		p.udpConn.Close()
+		p.udpConn = nil
+	}
+}
@@ -0,0 +1,87 @@
+package prober
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartStop(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Raw sockets require root")
+	}
+
+	p, err := New(Config{
+		BasePort: 32768,
+		SrcAddrs: []net.IP{net.IPv4(127, 0, 0, 1)},
+		Hops: []Hop{
+			{
+				Name:     "lo",
+				DstRange: []net.IP{net.IPv4(127, 0, 0, 1)},
+				SrcRange: []net.IP{net.IPv4(127, 0, 0, 1)},
+			},
+		},
+		TOS:                 TOS{Name: "BE"},
+		PPS:                 100,
+		MeasurementLengthMS: 100,
+		TimeoutMS:           50,
+	})
+	assert.NoError(t, err)
+
+	// Stopping a prober that was never started is a no-op
+	p.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, p.Start(ctx))
+		assert.True(t, p.Running())
+		assert.Error(t, p.Start(ctx), "starting a running prober must fail")
+
+		time.Sleep(50 * time.Millisecond)
+		p.Stop()
+		assert.False(t, p.Running())
+	}
+
+	assert.NoError(t, p.Start(ctx))
+	cancel()
+	assert.Eventually(t, func() bool {
+		return !p.Running()
+	}, time.Second, 10*time.Millisecond, "cancelling the context must stop the prober")
+	p.Stop()
+}
+
+func TestStartClosesSocketsOnError(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Raw sockets require root")
+	}
+
+	busy, err := net.ListenUDP("udp4", &net.UDPAddr{Port: int(maxPort)})
+	if err != nil {
+		t.Skipf("Unable to occupy port %d: %v", maxPort, err)
+	}
+	defer busy.Close()
+
+	p, err := New(Config{
+		BasePort: maxPort,
+		SrcAddrs: []net.IP{net.IPv4(127, 0, 0, 1)},
+		Hops: []Hop{
+			{
+				Name:     "lo",
+				DstRange: []net.IP{net.IPv4(127, 0, 0, 1)},
+			},
+		},
+		TOS:                 TOS{Name: "BE"},
+		PPS:                 100,
+		MeasurementLengthMS: 100,
+		TimeoutMS:           50,
+	})
+	assert.NoError(t, err)
+
+	assert.Error(t, p.Start(context.Background()))
+	assert.Nil(t, p.rawConn, "raw socket must be closed if the UDP socket can not be opened")
+	assert.False(t, p.Running())
+}
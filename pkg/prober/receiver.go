@@ -1,31 +1,58 @@
 package prober
 
 import (
+	"errors"
+	"os"
 	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func (p *Prober) receiver() {
+const (
+	// readTimeout is the interval in which the receiver checks if it should stop
+	readTimeout = 100 * time.Millisecond
+)
+
+// receiver receives returning probes until the sender is done and all probes in flight returned or timed out
+func (p *Prober) receiver(senderDone <-chan struct{}) {
+	defer p.wg.Done()
 	defer p.udpConn.Close()
 
+	var drainUntil time.Time
 	recvBuffer := make([]byte, p.mtu)
 	for {
-		select {
-		case <-p.stop:
+		if drainUntil.IsZero() {
+			select {
+			case <-senderDone:
+				drainUntil = time.Now().Add(time.Duration(p.cfg.TimeoutMS) * time.Millisecond)
+			default:
+			}
+		}
+
+		if !drainUntil.IsZero() && time.Now().After(drainUntil) {
 			return
-		default:
 		}
 
-		_, err := p.udpConn.Read(recvBuffer)
+		err := p.udpConn.SetReadDeadline(time.Now().Add(readTimeout))
+		if err != nil {
+			log.Errorf("Unable to set read deadline: %v", err)
+			return
+		}
+
+		n, meta, err := p.udpConn.ReadMeta(recvBuffer)
 		now := time.Now().UnixNano()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+
 		if err != nil {
 			log.Errorf("Unable to read from UDP socket: %v", err)
 			return
 		}
 
 		atomic.AddUint64(&p.probesReceived, 1)
+		p.checkTOS(meta.tos)
 
 		pkt, err := unmarshal(recvBuffer)
 		if err != nil {
@@ -33,23 +60,52 @@ func (p *Prober) receiver() {
 			return
 		}
 
+		if p.capturing() {
+			p.captureReceived(pkt, now, recvBuffer[:n], meta)
+		}
+
 		err = p.transitProbes.remove(pkt.Seq)
 		if err != nil {
 			// Probe was count as lost, so we ignore it from here on
 			continue
 		}
 
+		r := ProbeRecord{
+			Seq:    pkt.Seq,
+			SentTs: pkt.Ts,
+			RecvTs: now,
+			TOS:    meta.tos,
+			TTL:    meta.ttl,
+			Status: ProbeReceived,
+		}
+
 		rtt := now - pkt.Ts
 		if p.timedOut(rtt) {
 			// Probe arrived late. rttTimoutChecker() will clean up after it. So we ignore it from here on
 			atomic.AddUint64(&p.latePackets, 1)
+			r.Status = ProbeLate
+			p.record(r)
 			continue
 		}
 
+		p.record(r)
+
 		p.measurements.AddRecv(pkt.Ts, uint64(rtt), p.cfg.MeasurementLengthMS)
 	}
 }
 
+// checkTOS counts returning probes with a DSCP other than the configured one. ECN bits are ignored.
+func (p *Prober) checkTOS(tos int) {
+	if tos < 0 {
+		return
+	}
+
+	atomic.StoreUint32(&p.lastReceivedTOS, uint32(tos))
+	if uint8(tos)>>2 != p.cfg.TOS.Value>>2 {
+		atomic.AddUint64(&p.tosMismatches, 1)
+	}
+}
+
 func (p *Prober) timedOut(s int64) bool {
 	return s > int64(msToNS(p.cfg.TimeoutMS))
 }
@@ -0,0 +1,64 @@
+package prober
+
+import (
+	"net"
+)
+
+// ProbeStatus is the outcome of a single probe
+type ProbeStatus uint8
+
+const (
+	// ProbeReceived is the status of probes that returned within the timeout
+	ProbeReceived ProbeStatus = iota
+	// ProbeLate is the status of probes that returned after the timeout
+	ProbeLate
+	// ProbeLost is the status of probes that did not return
+	ProbeLost
+)
+
+func (s ProbeStatus) String() string {
+	switch s {
+	case ProbeReceived:
+		return "received"
+	case ProbeLate:
+		return "late"
+	default:
+		return "lost"
+	}
+}
+
+// ProbeRecord describes the outcome of a single probe
+type ProbeRecord struct {
+	Seq    uint64
+	SentTs int64
+	RecvTs int64 // 0 if the probe was lost
+	TOS    int   // TOS of the returned packet. -1 if unknown.
+	TTL    int   // TTL of the returned packet. -1 if unknown.
+	Status ProbeStatus
+}
+
+// ProbeRecorder gets notified about the outcome of every probe. Record must not block the prober.
+type ProbeRecorder interface {
+	Record(p *Prober, r ProbeRecord)
+}
+
+// SetRecorder sets the probe recorder. It must be called before the prober is started.
+func (p *Prober) SetRecorder(r ProbeRecorder) {
+	p.recorder = r
+}
+
+func (p *Prober) record(r ProbeRecord) {
+	if p.recorder != nil {
+		p.recorder.Record(p, r)
+	}
+}
+
+// ProbeAddrs returns the src address and the dst addresses of all hops of the probe with sequence number seq
+func (p *Prober) ProbeAddrs(seq uint64) (net.IP, []net.IP) {
+	dst := make([]net.IP, len(p.cfg.Hops))
+	for i := range p.cfg.Hops {
+		dst[i] = p.getDstAddr(i, seq)
+	}
+
+	return p.getSrcAddr(seq), dst
+}
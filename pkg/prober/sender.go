@@ -1,6 +1,7 @@
 package prober
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
@@ -11,18 +12,25 @@ import (
 	"golang.org/x/net/ipv4"
 )
 
-func (p *Prober) sender() {
+func (p *Prober) sender(ctx context.Context, done chan<- struct{}) {
+	defer p.wg.Done()
+	defer close(done)
 	defer p.rawConn.Close()
 
 	p.desynchronizeStartTime()
-	p.setLocalAddr()
+	err := WithNetNS(p.cfg.NetNS, p.setLocalAddr)
+	if err != nil {
+		log.Errorf("Unable to set local address: %v", err)
+	}
+
 	seq := uint64(0)
 	pr := probe{}
 	t := time.NewTicker(time.Second / time.Duration(p.cfg.PPS))
+	defer t.Stop()
 
 	for {
 		select {
-		case <-p.stop:
+		case <-ctx.Done():
 			return
 		case <-t.C:
 		}
@@ -37,8 +45,8 @@ func (p *Prober) sender() {
 
 		p.transitProbes.add(&pr)
 
-		tsAligned := pr.Ts - (pr.Ts % (int64(p.cfg.MeasurementLengthMS) * int64(time.Millisecond)))
-		p.measurements.AddSent(tsAligned)
+		p.measurements.AddSent(p.alignTs(pr.Ts))
+		p.lastSent = pr.Ts
 
 		srcAddr := p.getSrcAddr(seq)
 		dstAddr := p.cfg.Hops[0].getAddr(seq)
@@ -49,6 +57,10 @@ func (p *Prober) sender() {
 			continue
 		}
 
+		if p.capturing() {
+			p.captureSent(&pr, pkt, srcAddr, dstAddr)
+		}
+
 		atomic.AddUint64(&p.probesSent, 1)
 		seq++
 	}
@@ -66,7 +78,8 @@ func (p *Prober) sendPacket(payload []byte, src net.IP, dst net.IP) error {
 		Protocol: 47, //GRE
 	}
 
-	// Set source IP on socket in order to enforce "ip rule..." rules (possible Linux bug)
+	// Set source IP on socket in order to enforce "ip rule..." rules (possible Linux bug).
+	// Setups that can't rely on the source address should use FwMark based rules instead.
 	cm := ipv4.ControlMessage{}
 	if p.cfg.ConfiguredSrcAddr != nil {
 		cm.Src = p.cfg.ConfiguredSrcAddr
@@ -79,6 +92,11 @@ func (p *Prober) sendPacket(payload []byte, src net.IP, dst net.IP) error {
 	return nil
 }
 
+// alignTs returns the start of the measurement ts belongs to
+func (p *Prober) alignTs(ts int64) int64 {
+	return ts - ts%(int64(p.cfg.MeasurementLengthMS)*int64(time.Millisecond))
+}
+
 func (p *Prober) desynchronizeStartTime() {
 	time.Sleep(time.Duration(random(int64(p.cfg.TimeoutMS))) * time.Microsecond)
 }
@@ -1,8 +1,12 @@
 package prober
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/ipv4"
@@ -10,6 +14,9 @@ import (
 
 const (
 	maxPort = uint16(65535)
+
+	// oobSize is large enough for the IP_TOS and IP_TTL control messages
+	oobSize = 64
 )
 
 type rawSocket interface {
@@ -18,16 +25,54 @@ type rawSocket interface {
 }
 
 type udpSocket interface {
-	Read([]byte) (int, error)
+	ReadMeta([]byte) (int, packetMeta, error)
+	SetReadDeadline(time.Time) error
 	Close() error
 }
 
+// socketOptions are applied to every socket of a prober before it is bound
+type socketOptions struct {
+	bindDevice string
+	mark       uint32
+	recvMeta   bool // Receive the TOS and TTL of incoming packets where supported
+}
+
+// packetMeta is the IP header information of a received packet. Unknown values are -1.
+type packetMeta struct {
+	tos int
+	ttl int
+	src *net.UDPAddr
+}
+
+var unknownMeta = packetMeta{
+	tos: -1,
+	ttl: -1,
+}
+
+func (o socketOptions) control(network, address string, c syscall.RawConn) error {
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		err = o.apply(fd)
+	})
+	if cerr != nil {
+		return cerr
+	}
+
+	return err
+}
+
+func (o socketOptions) listenConfig() *net.ListenConfig {
+	return &net.ListenConfig{
+		Control: o.control,
+	}
+}
+
 type rawSockWrapper struct {
 	rawConn *ipv4.RawConn
 }
 
-func newRawSockWrapper() (*rawSockWrapper, error) {
-	c, err := net.ListenPacket("ip4:47", "0.0.0.0") // GRE for IPv4
+func newRawSockWrapper(opts socketOptions) (*rawSockWrapper, error) {
+	c, err := opts.listenConfig().ListenPacket(context.Background(), "ip4:47", "0.0.0.0") // GRE for IPv4
 	if err != nil {
 		return nil, fmt.Errorf("Unable to listen for GRE packets: %v", err)
 	}
@@ -53,34 +98,33 @@ func (s *rawSockWrapper) Close() error {
 type udpSockWrapper struct {
 	udpConn *net.UDPConn
 	port    uint16
+	oob     []byte
 }
 
-func newUDPSockWrapper(basePort uint16) (*udpSockWrapper, error) {
-	var udpConn *net.UDPConn
+func newUDPSockWrapper(basePort uint16, opts socketOptions) (*udpSockWrapper, error) {
+	var udpConn net.PacketConn
 
 	port := basePort
-	// Try to find a free UDP port
+	// Try to find a free UDP port. Other errors (e.g. a missing device) are not port specific.
 	for {
-		udpAddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf(":%d", port))
-		if err != nil {
-			return nil, fmt.Errorf("Unable to resolve address: %v", err)
+		var err error
+		udpConn, err = opts.listenConfig().ListenPacket(context.Background(), "udp4", fmt.Sprintf(":%d", port))
+		if err == nil {
+			break
 		}
 
-		udpConn, err = net.ListenUDP("udp", udpAddr)
-		if err != nil {
-			log.Debugf("UDP port %d is busy. Trying next one.", port)
-			port++
-			if port > maxPort {
-				return nil, fmt.Errorf("Unable to listen for UDP packets: %v", err)
-			}
-			continue
+		if !errors.Is(err, syscall.EADDRINUSE) || port == maxPort {
+			return nil, fmt.Errorf("Unable to listen for UDP packets: %v", err)
 		}
-		break
+
+		log.Debugf("UDP port %d is busy. Trying next one.", port)
+		port++
 	}
 
 	return &udpSockWrapper{
-		udpConn: udpConn,
+		udpConn: udpConn.(*net.UDPConn),
 		port:    port,
+		oob:     make([]byte, oobSize),
 	}, nil
 }
 
@@ -88,8 +132,20 @@ func (u *udpSockWrapper) getPort() uint16 {
 	return u.port
 }
 
-func (u *udpSockWrapper) Read(b []byte) (int, error) {
-	return u.udpConn.Read(b)
+// ReadMeta reads a packet and returns its TOS, TTL and source address. It must not be called concurrently.
+func (u *udpSockWrapper) ReadMeta(b []byte) (int, packetMeta, error) {
+	n, oobn, _, src, err := u.udpConn.ReadMsgUDP(b, u.oob)
+	if err != nil {
+		return n, unknownMeta, err
+	}
+
+	meta := parseMeta(u.oob[:oobn])
+	meta.src = src
+	return n, meta, nil
+}
+
+func (u *udpSockWrapper) SetReadDeadline(t time.Time) error {
+	return u.udpConn.SetReadDeadline(t)
 }
 
 func (u *udpSockWrapper) Close() error {
@@ -97,7 +153,7 @@ func (u *udpSockWrapper) Close() error {
 }
 
 func (p *Prober) initRawSocket() error {
-	rc, err := newRawSockWrapper()
+	rc, err := newRawSockWrapper(p.sendSocketOptions())
 	if err != nil {
 		return fmt.Errorf("Unable to create rack socket wrapper: %v", err)
 	}
@@ -107,7 +163,7 @@ func (p *Prober) initRawSocket() error {
 }
 
 func (p *Prober) initUDPSocket() error {
-	s, err := newUDPSockWrapper(p.cfg.BasePort)
+	s, err := newUDPSockWrapper(p.cfg.BasePort, p.receiveSocketOptions())
 	if err != nil {
 		return fmt.Errorf("Unable to get UDP socket wrapper: %v", err)
 	}
@@ -117,23 +173,46 @@ func (p *Prober) initUDPSocket() error {
 	return nil
 }
 
-func (p *Prober) setLocalAddr() error {
-	if p.cfg.ConfiguredSrcAddr != nil {
-		p.localAddr = p.cfg.ConfiguredSrcAddr
-		return nil
+func (p *Prober) sendSocketOptions() socketOptions {
+	return socketOptions{
+		bindDevice: p.cfg.BindDevice,
+		mark:       p.cfg.FwMark,
 	}
+}
 
-	addr, err := getLocalAddr(p.cfg.Hops[0].DstRange[0])
-	if err != nil {
-		return fmt.Errorf("Unable to get local address: %v", err)
+func (p *Prober) receiveSocketOptions() socketOptions {
+	o := p.sendSocketOptions()
+	o.recvMeta = true
+	if !p.cfg.FwMarkReceive {
+		o.mark = 0
 	}
 
+	return o
+}
+
+func (p *Prober) setLocalAddr() error {
+	addr := p.cfg.ConfiguredSrcAddr
+	if addr == nil {
+		var err error
+		addr, err = getLocalAddr(p.cfg.Hops[0].DstRange[0], p.sendSocketOptions())
+		if err != nil {
+			return fmt.Errorf("Unable to get local address: %v", err)
+		}
+	}
+
+	// Locked as Status reads the address concurrently
+	p.l.Lock()
 	p.localAddr = addr
+	p.l.Unlock()
 	return nil
 }
 
-func getLocalAddr(dest net.IP) (net.IP, error) {
-	conn, err := net.Dial("udp", fmt.Sprintf("%s:123", dest.String()))
+func getLocalAddr(dest net.IP, opts socketOptions) (net.IP, error) {
+	d := net.Dialer{
+		Control: opts.control,
+	}
+
+	conn, err := d.Dial("udp4", fmt.Sprintf("%s:123", dest.String()))
 	if err != nil {
 		return nil, fmt.Errorf("Dial failed: %v", err)
 	}
@@ -0,0 +1,74 @@
+//go:build linux
+
+package prober
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/sys/cpu"
+	"golang.org/x/sys/unix"
+)
+
+func (o socketOptions) apply(fd uintptr) error {
+	if o.bindDevice != "" {
+		err := unix.BindToDevice(int(fd), o.bindDevice)
+		if err != nil {
+			return fmt.Errorf("Unable to bind to device %q: %v", o.bindDevice, err)
+		}
+	}
+
+	if o.mark != 0 {
+		err := unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, int(o.mark))
+		if err != nil {
+			return fmt.Errorf("Unable to set mark %d: %v", o.mark, err)
+		}
+	}
+
+	if o.recvMeta {
+		err := unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVTOS, 1)
+		if err != nil {
+			return fmt.Errorf("Unable to enable IP_RECVTOS: %v", err)
+		}
+
+		err = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_RECVTTL, 1)
+		if err != nil {
+			return fmt.Errorf("Unable to enable IP_RECVTTL: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// parseMeta returns the TOS and TTL from the control messages of a received packet
+func parseMeta(oob []byte) packetMeta {
+	ret := unknownMeta
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return ret
+	}
+
+	for _, m := range msgs {
+		if m.Header.Level != unix.IPPROTO_IP {
+			continue
+		}
+
+		switch {
+		case m.Header.Type == unix.IP_TOS && len(m.Data) > 0:
+			ret.tos = int(m.Data[0])
+		case m.Header.Type == unix.IP_TTL && len(m.Data) >= 4:
+			ret.ttl = int(nativeEndian().Uint32(m.Data))
+		}
+	}
+
+	return ret
+}
+
+// nativeEndian returns the byte order of control message data
+func nativeEndian() binary.ByteOrder {
+	if cpu.IsBigEndian {
+		return binary.BigEndian
+	}
+
+	return binary.LittleEndian
+}
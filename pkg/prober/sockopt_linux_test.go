@@ -0,0 +1,58 @@
+//go:build linux
+
+package prober
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/ipv4"
+)
+
+func TestReadMeta(t *testing.T) {
+	s, err := newUDPSockWrapper(40000, socketOptions{recvMeta: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	c, err := net.Dial("udp4", fmt.Sprintf("127.0.0.1:%d", s.getPort()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	assert.NoError(t, ipv4.NewConn(c).SetTOS(0xb8))
+	assert.NoError(t, ipv4.NewConn(c).SetTTL(42))
+	_, err = c.Write([]byte("probe"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 100)
+	n, meta, err := s.ReadMeta(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "probe", string(buf[:n]))
+	assert.Equal(t, 0xb8, meta.tos)
+	assert.Equal(t, 42, meta.ttl)
+	assert.Equal(t, c.LocalAddr().String(), meta.src.String())
+}
+
+func TestNewUDPSockWrapper(t *testing.T) {
+	busy, err := newUDPSockWrapper(40100, socketOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer busy.Close()
+
+	s, err := newUDPSockWrapper(busy.getPort(), socketOptions{})
+	if assert.NoError(t, err, "Test #1: busy port is skipped") {
+		assert.Greater(t, s.getPort(), busy.getPort(), "Test #1: busy port is skipped")
+		s.Close()
+	}
+
+	_, err = newUDPSockWrapper(40200, socketOptions{bindDevice: "does-not-exist0"})
+	assert.Error(t, err, "Test #2: errors other than a busy port are returned at once")
+}
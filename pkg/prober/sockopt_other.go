@@ -0,0 +1,24 @@
+//go:build !linux
+
+package prober
+
+import (
+	"fmt"
+)
+
+func (o socketOptions) apply(fd uintptr) error {
+	if o.bindDevice != "" {
+		return fmt.Errorf("Binding to a device is only supported on Linux")
+	}
+
+	if o.mark != 0 {
+		return fmt.Errorf("Setting a fwmark is only supported on Linux")
+	}
+
+	return nil
+}
+
+// parseMeta returns unknown values as receiving the TOS and TTL is only supported on Linux
+func parseMeta(oob []byte) packetMeta {
+	return unknownMeta
+}
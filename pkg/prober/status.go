@@ -0,0 +1,91 @@
+package prober
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+)
+
+const (
+	// measurementHistory is the number of finished measurements kept per prober
+	measurementHistory = 300
+)
+
+// Status describes the runtime state of a prober
+type Status struct {
+	Running        bool
+	LocalAddr      net.IP
+	UDPPort        uint16
+	ProbesSent     uint64
+	ProbesReceived uint64
+	LatePackets    uint64
+	TOSMismatches  uint64 // Returned probes with a DSCP other than the configured one
+	LastTOS        uint8  // TOS of the last returned probe
+}
+
+// TimedMeasurement is a finished measurement and the start of its interval
+type TimedMeasurement struct {
+	Ts int64
+	*measurement.Measurement
+}
+
+// Status returns the runtime state of the prober
+func (p *Prober) Status() Status {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	return Status{
+		Running:        p.running(),
+		LocalAddr:      p.localAddr,
+		UDPPort:        p.dstUDPPort,
+		ProbesSent:     atomic.LoadUint64(&p.probesSent),
+		ProbesReceived: atomic.LoadUint64(&p.probesReceived),
+		LatePackets:    atomic.LoadUint64(&p.latePackets),
+		TOSMismatches:  atomic.LoadUint64(&p.tosMismatches),
+		LastTOS:        uint8(atomic.LoadUint32(&p.lastReceivedTOS)),
+	}
+}
+
+// TOSMismatches returns the number of returned probes with a DSCP other than the configured one
+// and the TOS of the last returned probe
+func (p *Prober) TOSMismatches() (uint64, uint8) {
+	return atomic.LoadUint64(&p.tosMismatches), uint8(atomic.LoadUint32(&p.lastReceivedTOS))
+}
+
+// LastMeasurements returns up to n of the most recent finished measurements, oldest first
+func (p *Prober) LastMeasurements(n int) []TimedMeasurement {
+	if n > measurementHistory {
+		n = measurementHistory
+	}
+
+	ret := make([]TimedMeasurement, 0, n)
+	ts := p.lastFinishedMeasurement()
+	for i := 0; i < n; i++ {
+		m := p.measurements.Get(ts)
+		if m != nil {
+			ret = append(ret, TimedMeasurement{
+				Ts:          ts,
+				Measurement: m,
+			})
+		}
+
+		ts -= p.measurementLengthNS()
+	}
+
+	for i, j := 0, len(ret)-1; i < j; i, j = i+1, j-1 {
+		ret[i], ret[j] = ret[j], ret[i]
+	}
+
+	return ret
+}
+
+func (p *Prober) measurementLengthNS() int64 {
+	return int64(p.cfg.MeasurementLengthMS) * int64(time.Millisecond)
+}
+
+// oldestRetainedMeasurement returns the timestamp of the oldest measurement kept in the history
+func (p *Prober) oldestRetainedMeasurement() int64 {
+	return p.lastFinishedMeasurement() - (measurementHistory-1)*p.measurementLengthNS()
+}
@@ -1,26 +1,39 @@
 package prober
 
 import (
+	"context"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func (p *Prober) rttTimeoutChecker() {
+func (p *Prober) rttTimeoutChecker(ctx context.Context) {
+	defer p.wg.Done()
+
 	t := time.NewTicker(time.Duration(p.cfg.MeasurementLengthMS) * time.Millisecond)
+	defer t.Stop()
 
 	for {
 		select {
-		case <-p.stop:
+		case <-ctx.Done():
 			return
 		case <-t.C:
 			timeout := p.cfg.MeasurementLengthMS * uint64(time.Millisecond)
 			maxTS := (uint64(time.Now().UnixNano()) - 3*timeout)
-			for s := range p.transitProbes.getLt(int64(maxTS)) {
+			for s, ts := range p.transitProbes.getLt(int64(maxTS)) {
 				err := p.transitProbes.remove(s)
 				if err != nil {
 					log.Infof("Probe %d timeouted: Unable to remove: %v", s, err)
+					continue
 				}
+
+				p.record(ProbeRecord{
+					Seq:    s,
+					SentTs: ts,
+					TOS:    -1,
+					TTL:    -1,
+					Status: ProbeLost,
+				})
 			}
 		}
 	}
@@ -0,0 +1,178 @@
+package recorder
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/exaring/matroschka-prober/pkg/internal/perprober"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/exaring/matroschka-prober/pkg/rotate"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	header = "seq,sent_ts_ns,recv_ts_ns,rtt_ns,src,dst,tos,ttl,status\n"
+)
+
+var (
+	// ErrNotFound is returned if a prober does not exist
+	ErrNotFound = perprober.ErrNotFound
+
+	// ErrActive is returned when starting a recording that is already active
+	ErrActive = perprober.ErrActive
+
+	// ErrInactive is returned when stopping a recording that is not active
+	ErrInactive = perprober.ErrInactive
+)
+
+// Config is the configuration of the recorder
+type Config struct {
+	Dir      string // Directory the files are written to. There is one file per prober.
+	MaxSize  int64  // Bytes after which a file is rotated
+	MaxFiles int    // Rotated files that are kept per prober
+}
+
+// Recorder writes every probe of selected probers as CSV. Recordings are started and stopped at runtime.
+type Recorder struct {
+	cfg        Config
+	recordings *perprober.Set[entry, *encoder]
+}
+
+type entry struct {
+	p *prober.Prober
+	r prober.ProbeRecord
+}
+
+// encoder writes probes as CSV lines to a rotated file
+type encoder struct {
+	*rotate.File
+}
+
+// New creates a new recorder
+func New(cfg Config, probers perprober.ProberLister) *Recorder {
+	return &Recorder{
+		cfg:        cfg,
+		recordings: perprober.NewSet[entry, *encoder]("recording", probers),
+	}
+}
+
+// Start starts recording the probes of a prober
+func (r *Recorder) Start(id string) error {
+	_, err := r.recordings.Start(id, perprober.Limits{}, func() (*encoder, error) {
+		err := os.MkdirAll(r.cfg.Dir, 0755)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create recording dir: %v", err)
+		}
+
+		f, err := rotate.Open(rotate.Config{
+			Path:     r.path(id),
+			MaxSize:  r.cfg.MaxSize,
+			MaxFiles: r.cfg.MaxFiles,
+			Header:   []byte(header),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open recording: %v", err)
+		}
+
+		return &encoder{File: f}, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Started recording probes of prober %q to %q", id, r.path(id))
+	return nil
+}
+
+// path returns the path of the file of a prober
+func (r *Recorder) path(id string) string {
+	return filepath.Join(r.cfg.Dir, perprober.FileName(id)+".csv")
+}
+
+// Stop stops recording the probes of a prober. It blocks until all queued probes are written.
+func (r *Recorder) Stop(id string) error {
+	return r.recordings.Stop(id)
+}
+
+// Forget stops the recording of a removed prober
+func (r *Recorder) Forget(id string) {
+	r.recordings.Forget(id)
+}
+
+// Close stops all recordings
+func (r *Recorder) Close() {
+	r.recordings.Close()
+}
+
+// Active returns the sorted IDs of all probers that are recorded
+func (r *Recorder) Active() []string {
+	return r.recordings.IDs()
+}
+
+// Record queues a probe if its prober is recorded
+func (r *Recorder) Record(p *prober.Prober, rec prober.ProbeRecord) {
+	err := r.recordings.Write(p.ID(), entry{p: p, r: rec})
+	if errors.Is(err, perprober.ErrQueueFull) {
+		log.Errorf("Recording queue of prober %q is full. Dropping probe %d", p.ID(), rec.Seq)
+	}
+}
+
+// Encode writes a probe as CSV line
+func (e *encoder) Encode(v entry) error {
+	_, err := e.Write(line(v.p, v.r))
+	return err
+}
+
+// line formats a probe as CSV line. Values that are unknown are left empty.
+func line(p *prober.Prober, r prober.ProbeRecord) []byte {
+	src, dst := p.ProbeAddrs(r.Seq)
+
+	b := make([]byte, 0, 128)
+	b = strconv.AppendUint(b, r.Seq, 10)
+	b = append(b, ',')
+	b = strconv.AppendInt(b, r.SentTs, 10)
+	b = append(b, ',')
+	if r.Status != prober.ProbeLost {
+		b = strconv.AppendInt(b, r.RecvTs, 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, r.RecvTs-r.SentTs, 10)
+	} else {
+		b = append(b, ',')
+	}
+	b = append(b, ',')
+	b = append(b, src.String()...)
+	b = append(b, ',')
+	b = appendAddrs(b, dst)
+	b = append(b, ',')
+	b = appendKnown(b, r.TOS)
+	b = append(b, ',')
+	b = appendKnown(b, r.TTL)
+	b = append(b, ',')
+	b = append(b, r.Status.String()...)
+
+	return append(b, '\n')
+}
+
+// appendAddrs appends the addresses separated by spaces
+func appendAddrs(b []byte, addrs []net.IP) []byte {
+	for i, a := range addrs {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, a.String()...)
+	}
+
+	return b
+}
+
+func appendKnown(b []byte, v int) []byte {
+	if v < 0 {
+		return b
+	}
+
+	return strconv.AppendInt(b, int64(v), 10)
+}
@@ -0,0 +1,62 @@
+package recorder
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeLister []string
+
+func (f fakeLister) IDs() []string {
+	return f
+}
+
+func TestRecorder(t *testing.T) {
+	p, err := prober.New(prober.Config{
+		PathName: "a-b",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		SrcAddrs: []net.IP{net.ParseIP("169.254.0.1"), net.ParseIP("169.254.0.2")},
+		Hops: []prober.Hop{
+			{Name: "a", DstRange: []net.IP{net.ParseIP("10.0.0.1")}},
+			{Name: "b", DstRange: []net.IP{net.ParseIP("10.0.1.1"), net.ParseIP("10.0.1.2")}},
+		},
+	})
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	r := New(Config{
+		Dir:      dir,
+		MaxSize:  1 << 20,
+		MaxFiles: 1,
+	}, fakeLister{"a-b@BE"})
+
+	assert.Equal(t, ErrNotFound, r.Start("x@BE"), "Test #1: unknown prober")
+	assert.Equal(t, ErrInactive, r.Stop("a-b@BE"), "Test #2: stop inactive recording")
+
+	r.Record(p, prober.ProbeRecord{Seq: 0, SentTs: 100, RecvTs: 150, TOS: 0, TTL: 62})
+	assert.NoError(t, r.Start("a-b@BE"))
+	assert.Equal(t, ErrActive, r.Start("a-b@BE"), "Test #3: start active recording")
+	assert.Equal(t, []string{"a-b@BE"}, r.Active())
+
+	r.Record(p, prober.ProbeRecord{Seq: 1, SentTs: 200, RecvTs: 250, TOS: 0xb8, TTL: 62, Status: prober.ProbeReceived})
+	r.Record(p, prober.ProbeRecord{Seq: 2, SentTs: 300, RecvTs: 900, TOS: -1, TTL: -1, Status: prober.ProbeLate})
+	r.Record(p, prober.ProbeRecord{Seq: 3, SentTs: 400, TOS: -1, TTL: -1, Status: prober.ProbeLost})
+	assert.NoError(t, r.Stop("a-b@BE"))
+	assert.Empty(t, r.Active())
+
+	b, err := os.ReadFile(filepath.Join(dir, "a-b@BE.csv"))
+	assert.NoError(t, err)
+
+	expected := "seq,sent_ts_ns,recv_ts_ns,rtt_ns,src,dst,tos,ttl,status\n" +
+		"1,200,250,50,169.254.0.2,10.0.0.1 10.0.1.2,184,62,received\n" +
+		"2,300,900,600,169.254.0.1,10.0.0.1 10.0.1.1,,,late\n" +
+		"3,400,,,169.254.0.2,10.0.0.1 10.0.1.2,,,lost\n"
+	assert.Equal(t, expected, string(b), "Test #4: only probes while recording")
+}
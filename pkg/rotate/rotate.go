@@ -0,0 +1,150 @@
+package rotate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Config is the configuration of a rotated file
+type Config struct {
+	Path     string
+	MaxSize  int64  // Bytes after which the file is rotated
+	MaxFiles int    // Rotated files that are kept. The file is truncated instead if it is 0.
+	Header   []byte // Written to the start of every new file
+}
+
+// File is a buffered file that is rotated by size. Rotated files are named after the file
+// with a suffix, .1 being the most recent.
+type File struct {
+	cfg  Config
+	file *os.File
+	w    *bufio.Writer
+	size int64
+}
+
+// Open opens a file for appending. It is created if it does not exist.
+func Open(cfg Config) (*File, error) {
+	f := &File{
+		cfg: cfg,
+	}
+
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to open file: %v", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Unable to stat file: %v", err)
+	}
+
+	f.file = file
+	f.w = bufio.NewWriter(file)
+	f.size = info.Size()
+
+	if f.size == 0 && len(f.cfg.Header) > 0 {
+		n, err := f.w.Write(f.cfg.Header)
+		f.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("Unable to write header: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// Write writes b to the file. The file is rotated before if b would exceed the maximum size,
+// so b is never split across files.
+func (f *File) Write(b []byte) (int, error) {
+	if f.file == nil {
+		return 0, errors.New("File is closed")
+	}
+
+	if f.size > int64(len(f.cfg.Header)) && f.size+int64(len(b)) > f.cfg.MaxSize {
+		err := f.rotate()
+		if err != nil {
+			return 0, fmt.Errorf("Unable to rotate: %v", err)
+		}
+	}
+
+	n, err := f.w.Write(b)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the rotated files by one, dropping the oldest, and starts a new file
+func (f *File) rotate() error {
+	err := f.Close()
+	if err != nil {
+		return err
+	}
+
+	if f.cfg.MaxFiles < 1 {
+		err = os.Truncate(f.cfg.Path, 0)
+		if err != nil {
+			return err
+		}
+
+		return f.open()
+	}
+
+	err = os.Remove(f.rotatedPath(f.cfg.MaxFiles))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for i := f.cfg.MaxFiles; i > 0; i-- {
+		err := os.Rename(f.rotatedPath(i-1), f.rotatedPath(i))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return f.open()
+}
+
+// rotatedPath returns the path of the nth rotated file. 0 is the current file.
+func (f *File) rotatedPath(n int) string {
+	if n == 0 {
+		return f.cfg.Path
+	}
+
+	return fmt.Sprintf("%s.%d", f.cfg.Path, n)
+}
+
+// Flush writes buffered data to the file
+func (f *File) Flush() error {
+	if f.file == nil {
+		return nil
+	}
+
+	return f.w.Flush()
+}
+
+// Close flushes and closes the file
+func (f *File) Close() error {
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.w.Flush()
+	cerr := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return err
+	}
+
+	return cerr
+}
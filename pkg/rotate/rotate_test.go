@@ -0,0 +1,87 @@
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.csv")
+	f, err := Open(Config{
+		Path:     path,
+		MaxSize:  10,
+		MaxFiles: 2,
+		Header:   []byte("h\n"),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, l := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddddddddd\n", "eeee\n"} {
+		_, err := f.Write([]byte(l))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Close())
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{
+			name:     "Test #1: current file",
+			file:     path,
+			expected: "h\neeee\n",
+		},
+		{
+			name:     "Test #2: lines longer than the maximum size are not split",
+			file:     path + ".1",
+			expected: "h\ndddddddddd\n",
+		},
+		{
+			name:     "Test #3: oldest rotated file",
+			file:     path + ".2",
+			expected: "h\ncccc\n",
+		},
+	}
+
+	for _, test := range tests {
+		b, err := os.ReadFile(test.file)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, string(b), test.name)
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "Test #4: oldest file is dropped")
+
+	_, err = f.Write([]byte("x"))
+	assert.Error(t, err, "Test #5: write to closed file")
+}
+
+func TestTruncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probes.csv")
+	f, err := Open(Config{
+		Path:    path,
+		MaxSize: 10,
+		Header:  []byte("h\n"),
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, l := range []string{"aaaa\n", "bbbb\n", "cccc\n"} {
+		_, err := f.Write([]byte(l))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, f.Close())
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "h\ncccc\n", string(b), "Test #1: file is truncated without rotated files")
+
+	_, err = os.Stat(path + ".1")
+	assert.True(t, os.IsNotExist(err), "Test #2: no rotated file is kept")
+}
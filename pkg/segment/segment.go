@@ -0,0 +1,220 @@
+package segment
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricPrefix = "matroschka_"
+
+	// bucketHistory is the number of buckets kept per prober to align with the buckets of the other path
+	bucketHistory = 10
+)
+
+// Config is the configuration of the segment calculator
+type Config struct {
+	Segments []Segment
+}
+
+// Segment is a segment whose RTT is derived as the difference of the RTTs of two paths
+type Segment struct {
+	Name     string
+	Path     string
+	Subtract string
+}
+
+// Result is the RTT of a segment in one bucket. Values may be negative if the RTT of the
+// subtracted path exceeds the RTT of the path, e.g. due to queueing.
+type Result struct {
+	Segment   string
+	Class     string
+	Ts        int64 // Start of the bucket
+	RTTMin    int64 // Nanoseconds
+	RTTMedian int64 // Nanoseconds
+}
+
+// Calculator derives segment RTTs from time aligned finished buckets of two paths
+type Calculator struct {
+	cfg     Config
+	buckets map[string][]bucket
+	results map[resultKey]Result
+	l       sync.RWMutex
+}
+
+type bucket struct {
+	ts     int64
+	min    uint64
+	median uint64
+}
+
+type resultKey struct {
+	segment string
+	class   string
+}
+
+// New creates a new segment calculator
+func New(cfg Config) *Calculator {
+	return &Calculator{
+		cfg:     cfg,
+		buckets: make(map[string][]bucket),
+		results: make(map[resultKey]Result),
+	}
+}
+
+// Update replaces the configuration. Results of segments that still exist unchanged are kept.
+func (c *Calculator) Update(cfg Config) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	segments := make(map[string]Segment, len(cfg.Segments))
+	for _, s := range cfg.Segments {
+		segments[s.Name] = s
+	}
+
+	for _, s := range c.cfg.Segments {
+		if n, ok := segments[s.Name]; !ok || n != s {
+			c.deleteResults(func(k resultKey) bool {
+				return k.segment == s.Name
+			})
+		}
+	}
+
+	c.cfg = cfg
+}
+
+// Forget drops the buckets of a removed prober and the results of all segments it is part of
+func (c *Calculator) Forget(id string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	delete(c.buckets, id)
+
+	for _, s := range c.cfg.Segments {
+		c.deleteResults(func(k resultKey) bool {
+			return k.segment == s.Name && (proberID(s.Path, k.class) == id || proberID(s.Subtract, k.class) == id)
+		})
+	}
+}
+
+// deleteResults deletes the results matching f. The caller must hold the lock.
+func (c *Calculator) deleteResults(f func(k resultKey) bool) {
+	for k := range c.results {
+		if f(k) {
+			delete(c.results, k)
+		}
+	}
+}
+
+// Observe records a finished measurement and updates all segments of the probers path
+// once the bucket of the other path with the same timestamp is available. A bucket without
+// replies drops the results of these segments as their RTT is unknown.
+func (c *Calculator) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	class := p.TOS().Name
+	if m.Received == 0 {
+		for _, s := range c.cfg.Segments {
+			if p.PathName() != s.Path && p.PathName() != s.Subtract {
+				continue
+			}
+
+			delete(c.results, resultKey{segment: s.Name, class: class})
+		}
+
+		return
+	}
+
+	b := bucket{
+		ts:     ts,
+		min:    m.RTTMin,
+		median: m.RTTQuantile(0.5),
+	}
+
+	buckets := append(c.buckets[p.ID()], b)
+	if len(buckets) > bucketHistory {
+		buckets = buckets[len(buckets)-bucketHistory:]
+	}
+	c.buckets[p.ID()] = buckets
+
+	for _, s := range c.cfg.Segments {
+		var path, sub bucket
+		var ok bool
+		switch p.PathName() {
+		case s.Path:
+			path = b
+			sub, ok = c.bucket(proberID(s.Subtract, class), ts)
+		case s.Subtract:
+			sub = b
+			path, ok = c.bucket(proberID(s.Path, class), ts)
+		}
+
+		if !ok {
+			continue
+		}
+
+		c.results[resultKey{segment: s.Name, class: class}] = Result{
+			Segment:   s.Name,
+			Class:     class,
+			Ts:        ts,
+			RTTMin:    int64(path.min) - int64(sub.min),
+			RTTMedian: int64(path.median) - int64(sub.median),
+		}
+	}
+}
+
+func (c *Calculator) bucket(id string, ts int64) (bucket, bool) {
+	for _, b := range c.buckets[id] {
+		if b.ts == ts {
+			return b, true
+		}
+	}
+
+	return bucket{}, false
+}
+
+// proberID returns the ID of the prober of a path and class as returned by prober.ID
+func proberID(path string, class string) string {
+	return path + "@" + class
+}
+
+// Results returns the latest result of all segments and classes
+func (c *Calculator) Results() []Result {
+	c.l.RLock()
+	defer c.l.RUnlock()
+
+	ret := make([]Result, 0, len(c.results))
+	for _, r := range c.results {
+		ret = append(ret, r)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Segment != ret[j].Segment {
+			return ret[i].Segment < ret[j].Segment
+		}
+
+		return ret[i].Class < ret[j].Class
+	})
+
+	return ret
+}
+
+// Describe is required by prometheus interface
+func (c *Calculator) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect collects the segment RTTs and sends them to prometheus
+func (c *Calculator) Collect(ch chan<- prometheus.Metric) {
+	minDesc := prometheus.NewDesc(metricPrefix+"segment_rtt_min", "round-trip time minimum of a segment in nanoseconds derived as difference of two paths", []string{"segment", "tos"}, nil)
+	medianDesc := prometheus.NewDesc(metricPrefix+"segment_rtt_median", "round-trip time median of a segment in nanoseconds derived as difference of two paths", []string{"segment", "tos"}, nil)
+
+	for _, r := range c.Results() {
+		ch <- prometheus.MustNewConstMetric(minDesc, prometheus.GaugeValue, float64(r.RTTMin), r.Segment, r.Class)
+		ch <- prometheus.MustNewConstMetric(medianDesc, prometheus.GaugeValue, float64(r.RTTMedian), r.Segment, r.Class)
+	}
+}
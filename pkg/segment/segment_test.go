@@ -0,0 +1,118 @@
+package segment
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProber(t *testing.T, path string) *prober.Prober {
+	p, err := prober.New(prober.Config{
+		PathName: path,
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		Hops: []prober.Hop{
+			{Name: "a"},
+		},
+	})
+	assert.NoError(t, err)
+
+	return p
+}
+
+func TestCalculator(t *testing.T) {
+	c := New(Config{
+		Segments: []Segment{
+			{Name: "b-c", Path: "a-b-c", Subtract: "a-b"},
+		},
+	})
+
+	long := newProber(t, "a-b-c")
+	short := newProber(t, "a-b")
+	other := newProber(t, "x")
+
+	c.Observe(long, 1000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 5, RTTs: []uint64{5, 9, 7}})
+	c.Observe(other, 1000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 1, RTTs: []uint64{1}})
+	assert.Empty(t, c.Results(), "Test #1: subtracted path not observed yet")
+
+	c.Observe(short, 2000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 1, RTTs: []uint64{1}})
+	assert.Empty(t, c.Results(), "Test #2: buckets not aligned")
+
+	c.Observe(short, 1000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 2, RTTs: []uint64{2, 3, 4}})
+	assert.Equal(t, []Result{
+		{Segment: "b-c", Class: "BE", Ts: 1000, RTTMin: 3, RTTMedian: 4},
+	}, c.Results(), "Test #3: aligned buckets")
+
+	c.Observe(long, 2000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 0, RTTs: []uint64{0}})
+	assert.Equal(t, []Result{
+		{Segment: "b-c", Class: "BE", Ts: 2000, RTTMin: -1, RTTMedian: -1},
+	}, c.Results(), "Test #4: negative differences")
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	expected := `
+# HELP matroschka_segment_rtt_median round-trip time median of a segment in nanoseconds derived as difference of two paths
+# TYPE matroschka_segment_rtt_median gauge
+matroschka_segment_rtt_median{segment="b-c",tos="BE"} -1
+# HELP matroschka_segment_rtt_min round-trip time minimum of a segment in nanoseconds derived as difference of two paths
+# TYPE matroschka_segment_rtt_min gauge
+matroschka_segment_rtt_min{segment="b-c",tos="BE"} -1
+`
+	assert.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)))
+
+	c.Forget(other.ID())
+	assert.Len(t, c.Results(), 1, "Test #5: forgetting an unrelated prober")
+
+	c.Forget(short.ID())
+	assert.Empty(t, c.Results(), "Test #6: forgotten subtracted prober")
+
+	c.Observe(short, 2000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 1, RTTs: []uint64{1}})
+	assert.Len(t, c.Results(), 1)
+	c.Update(Config{
+		Segments: []Segment{
+			{Name: "b-c", Path: "a-b-c", Subtract: "x"},
+		},
+	})
+	assert.Empty(t, c.Results(), "Test #7: changed segment")
+
+	c.Observe(other, 2000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 1, RTTs: []uint64{1}})
+	assert.Len(t, c.Results(), 1)
+	c.Update(Config{})
+	assert.Empty(t, c.Results(), "Test #8: removed segment")
+}
+
+func TestCalculatorLoss(t *testing.T) {
+	c := New(Config{
+		Segments: []Segment{
+			{Name: "b-c", Path: "a-b-c", Subtract: "a-b"},
+		},
+	})
+
+	long := newProber(t, "a-b-c")
+	short := newProber(t, "a-b")
+
+	c.Observe(long, 1000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 5, RTTs: []uint64{5}})
+	c.Observe(short, 1000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 2, RTTs: []uint64{2}})
+	assert.Len(t, c.Results(), 1)
+
+	c.Observe(long, 2000, &measurement.Measurement{Sent: 3})
+	assert.Empty(t, c.Results(), "Test #1: full loss of the path")
+
+	c.Observe(short, 2000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 2, RTTs: []uint64{2}})
+	assert.Empty(t, c.Results(), "Test #2: other path of the lost bucket")
+
+	c.Observe(short, 3000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 2, RTTs: []uint64{2}})
+	c.Observe(long, 3000, &measurement.Measurement{Sent: 3, Received: 3, RTTMin: 6, RTTs: []uint64{6}})
+	assert.Equal(t, []Result{
+		{Segment: "b-c", Class: "BE", Ts: 3000, RTTMin: 4, RTTMedian: 4},
+	}, c.Results(), "Test #3: recovered path")
+
+	c.Observe(short, 4000, &measurement.Measurement{Sent: 3})
+	assert.Empty(t, c.Results(), "Test #4: full loss of the subtracted path")
+}
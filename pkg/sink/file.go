@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/exaring/matroschka-prober/pkg/internal/perprober"
+	"github.com/exaring/matroschka-prober/pkg/rotate"
+	log "github.com/sirupsen/logrus"
+)
+
+// FileConfig is the configuration of the file sink
+type FileConfig struct {
+	Path     string
+	MaxSize  int64 // Bytes after which the file is rotated
+	MaxFiles int   // Rotated files that are kept
+}
+
+// FileSink writes results as JSON lines to a file that is rotated by size.
+// Rotated files are named after the file with a suffix, .1 being the most recent.
+type FileSink struct {
+	w *perprober.Writer[*Result]
+}
+
+// fileEncoder writes results as JSON lines
+type fileEncoder struct {
+	*rotate.File
+}
+
+type record struct {
+	Time      time.Time         `json:"time"`
+	Prober    string            `json:"prober"`
+	Path      string            `json:"path"`
+	Hops      string            `json:"hops"`
+	Class     string            `json:"class"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Sent      uint64            `json:"sent"`
+	Received  uint64            `json:"received"`
+	LossRatio float64           `json:"loss_ratio"`
+	RTTMin    uint64            `json:"rtt_min_ns,omitempty"`
+	RTTAvg    uint64            `json:"rtt_avg_ns,omitempty"`
+	RTTMax    uint64            `json:"rtt_max_ns,omitempty"`
+	RTTP50    uint64            `json:"rtt_p50_ns,omitempty"`
+	RTTP90    uint64            `json:"rtt_p90_ns,omitempty"`
+	RTTP99    uint64            `json:"rtt_p99_ns,omitempty"`
+	Jitter    uint64            `json:"jitter_ns,omitempty"`
+}
+
+func newRecord(r *Result) *record {
+	m := r.Measurement
+	rec := &record{
+		Time:      time.Unix(0, r.Ts).UTC(),
+		Prober:    r.Prober,
+		Path:      r.Path,
+		Hops:      r.Hops,
+		Class:     r.Class,
+		Sent:      m.Sent,
+		Received:  m.Received,
+		LossRatio: m.Loss(),
+	}
+
+	for _, l := range r.Labels {
+		if rec.Labels == nil {
+			rec.Labels = make(map[string]string)
+		}
+		rec.Labels[l.Key] = l.Value
+	}
+
+	if m.Received > 0 {
+		rec.RTTMin = m.RTTMin
+		rec.RTTAvg = m.RTTAvg()
+		rec.RTTMax = m.RTTMax
+		rec.RTTP50 = m.RTTQuantile(0.5)
+		rec.RTTP90 = m.RTTQuantile(0.9)
+		rec.RTTP99 = m.RTTQuantile(0.99)
+		rec.Jitter = m.Jitter()
+	}
+
+	return rec
+}
+
+// NewFile creates a new file sink. The file is appended to if it exists.
+func NewFile(cfg FileConfig) (*FileSink, error) {
+	f, err := rotate.Open(rotate.Config{
+		Path:     cfg.Path,
+		MaxSize:  cfg.MaxSize,
+		MaxFiles: cfg.MaxFiles,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open result file: %v", err)
+	}
+
+	return &FileSink{
+		w: perprober.NewWriter[*Result]("result file", &fileEncoder{File: f}, perprober.Limits{}),
+	}, nil
+}
+
+// Write queues a result without blocking the prober
+func (s *FileSink) Write(r *Result) {
+	err := s.w.Write(r)
+	if err != nil {
+		log.Errorf("Result file queue is full. Dropping result of prober %q", r.Prober)
+	}
+}
+
+// Run writes queued results until ctx is cancelled. The file is flushed whenever the queue is empty.
+func (s *FileSink) Run(ctx context.Context) {
+	go s.w.Run()
+	<-ctx.Done()
+	s.w.Stop()
+}
+
+// Encode writes a result as JSON line
+func (e *fileEncoder) Encode(r *Result) error {
+	b, err := json.Marshal(newRecord(r))
+	if err != nil {
+		return fmt.Errorf("Unable to marshal result: %v", err)
+	}
+
+	_, err = e.Write(append(b, '\n'))
+	return err
+}
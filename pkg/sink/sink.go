@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"context"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+)
+
+// Result is a finished bucket of a prober. Sinks must not modify it as it is shared by all sinks.
+type Result struct {
+	Prober      string // ID of the prober
+	Path        string // Name of the path in the config
+	Hops        string // Hop names as used in the `path` label
+	Class       string
+	Labels      []prober.Label // Static labels
+	Ts          int64          // Start of the bucket in nanoseconds
+	Measurement *measurement.Measurement
+}
+
+// Sink receives every finished bucket of all probers
+type Sink interface {
+	// Write passes a result to the sink. It must not block the prober.
+	Write(r *Result)
+	// Run processes results until ctx is cancelled
+	Run(ctx context.Context)
+}
+
+// Dispatcher passes finished measurements of all probers to the sinks
+type Dispatcher struct {
+	sinks []Sink
+}
+
+// NewDispatcher creates a new dispatcher
+func NewDispatcher(sinks ...Sink) *Dispatcher {
+	return &Dispatcher{
+		sinks: sinks,
+	}
+}
+
+// Observe passes a finished measurement to all sinks
+func (d *Dispatcher) Observe(p *prober.Prober, ts int64, m *measurement.Measurement) {
+	r := &Result{
+		Prober:      p.ID(),
+		Path:        p.PathName(),
+		Hops:        p.Path(),
+		Class:       p.TOS().Name,
+		Labels:      p.Config().StaticLabels,
+		Ts:          ts,
+		Measurement: m,
+	}
+
+	for _, s := range d.sinks {
+		s.Write(r)
+	}
+}
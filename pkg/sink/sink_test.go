@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/measurement"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSink struct {
+	results []*Result
+}
+
+func (f *fakeSink) Write(r *Result) {
+	f.results = append(f.results, r)
+}
+
+func (f *fakeSink) Run(ctx context.Context) {
+}
+
+func TestDispatcher(t *testing.T) {
+	p, err := prober.New(prober.Config{
+		PathName: "p1",
+		TOS: prober.TOS{
+			Name: "BE",
+		},
+		Hops: []prober.Hop{
+			{Name: "a"},
+			{Name: "b"},
+		},
+		StaticLabels: []prober.Label{
+			{Key: "site", Value: "fra01"},
+		},
+	})
+	assert.NoError(t, err)
+
+	s1, s2 := &fakeSink{}, &fakeSink{}
+	d := NewDispatcher(s1, s2)
+	m := &measurement.Measurement{Sent: 1}
+	d.Observe(p, 1000, m)
+
+	expected := []*Result{
+		{
+			Prober:      "p1@BE",
+			Path:        "p1",
+			Hops:        "a-b",
+			Class:       "BE",
+			Labels:      []prober.Label{{Key: "site", Value: "fra01"}},
+			Ts:          1000,
+			Measurement: m,
+		},
+	}
+	assert.Equal(t, expected, s1.results)
+	assert.Equal(t, expected, s2.results)
+}
+
+func TestFileSink(t *testing.T) {
+	// A line is about 280 bytes so every file holds one line
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	s, err := NewFile(FileConfig{
+		Path:     path,
+		MaxSize:  300,
+		MaxFiles: 2,
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i := 0; i < 4; i++ {
+		s.Write(&Result{
+			Prober: "p1@BE",
+			Path:   "p1",
+			Hops:   "a-b",
+			Class:  "BE",
+			Labels: []prober.Label{{Key: "site", Value: "fra01"}},
+			Ts:     int64(i) * 1e9,
+			Measurement: &measurement.Measurement{
+				Sent:     4,
+				Received: 3,
+				RTTMin:   1000,
+				RTTMax:   3000,
+				RTTSum:   6000,
+				RTTs:     []uint64{1000, 2000, 3000},
+			},
+		})
+	}
+
+	cancel()
+	s.Run(ctx)
+
+	line := func(sec string) string {
+		return `{"time":"1970-01-01T00:00:0` + sec + `Z","prober":"p1@BE","path":"p1","hops":"a-b","class":"BE","labels":{"site":"fra01"},"sent":4,"received":3,"loss_ratio":0.25,"rtt_min_ns":1000,"rtt_avg_ns":2000,"rtt_max_ns":3000,"rtt_p50_ns":2000,"rtt_p90_ns":3000,"rtt_p99_ns":3000,"jitter_ns":1000}` + "\n"
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{
+			name:     "Test #1: current file",
+			file:     path,
+			expected: line("3"),
+		},
+		{
+			name:     "Test #2: most recent rotated file",
+			file:     path + ".1",
+			expected: line("2"),
+		},
+		{
+			name:     "Test #3: oldest rotated file",
+			file:     path + ".2",
+			expected: line("1"),
+		},
+	}
+
+	for _, test := range tests {
+		b, err := os.ReadFile(test.file)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, string(b), test.name)
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "Test #4: oldest file is dropped")
+}
@@ -0,0 +1,99 @@
+package tomography
+
+import (
+	"math"
+)
+
+const (
+	// ridge regularises underdetermined systems. Links that can not be told apart share the estimate evenly.
+	ridge = 1e-6
+)
+
+// solve returns the non-negative least squares solution x of a*x = y. Columns whose
+// unconstrained solution is negative are fixed to 0 and the system is solved again.
+func solve(a [][]float64, y []float64, n int) []float64 {
+	active := make([]bool, n)
+	for j := range active {
+		active[j] = true
+	}
+
+	for {
+		x := solveRidge(a, y, active)
+
+		negative := false
+		for j := range x {
+			if active[j] && x[j] < 0 {
+				active[j] = false
+				negative = true
+			}
+		}
+
+		if !negative {
+			return x
+		}
+	}
+}
+
+// solveRidge solves the regularised normal equations (A'A + ridge*I) x = A'y for the active columns
+func solveRidge(a [][]float64, y []float64, active []bool) []float64 {
+	n := len(active)
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n+1)
+		if !active[i] {
+			m[i][i] = 1
+			continue
+		}
+
+		m[i][i] = ridge
+	}
+
+	for r := range a {
+		for i := 0; i < n; i++ {
+			if !active[i] || a[r][i] == 0 {
+				continue
+			}
+
+			for j := 0; j < n; j++ {
+				if active[j] {
+					m[i][j] += a[r][i] * a[r][j]
+				}
+			}
+			m[i][n] += a[r][i] * y[r]
+		}
+	}
+
+	return gauss(m)
+}
+
+// gauss solves the linear system given as augmented matrix m using gaussian elimination with partial pivoting
+func gauss(m [][]float64) []float64 {
+	n := len(m)
+	for c := 0; c < n; c++ {
+		p := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(m[r][c]) > math.Abs(m[p][c]) {
+				p = r
+			}
+		}
+		m[c], m[p] = m[p], m[c]
+
+		for r := c + 1; r < n; r++ {
+			f := m[r][c] / m[c][c]
+			for k := c; k <= n; k++ {
+				m[r][k] -= f * m[c][k]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		s := m[r][n]
+		for k := r + 1; k < n; k++ {
+			s -= m[r][k] * x[k]
+		}
+		x[r] = s / m[r][r]
+	}
+
+	return x
+}
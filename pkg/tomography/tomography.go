@@ -0,0 +1,187 @@
+package tomography
+
+import (
+	"math"
+	"sort"
+
+	"github.com/exaring/matroschka-prober/pkg/correlation"
+	"github.com/exaring/matroschka-prober/pkg/manager"
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricPrefix = "matroschka_"
+
+	// maxLossRatio caps the loss of a path as a path without any returned probe has an infinite log loss
+	maxLossRatio = 0.999
+)
+
+// Source provides the probers and their measurements
+type Source interface {
+	Status() []manager.ProberStatus
+	Measurements(id string, n int) ([]prober.TimedMeasurement, error)
+}
+
+// Link is a directed link between two routers or a router and the prober
+type Link struct {
+	From string
+	To   string
+}
+
+// Observation is the loss and average RTT of a path in one bucket
+type Observation struct {
+	Class     string
+	Links     []Link // In order of traversal. Links traversed more than once appear more than once.
+	LossRatio float64
+	RTT       float64 // Average RTT in nanoseconds. Only used if HasRTT is set.
+	HasRTT    bool
+}
+
+// Estimate is the estimated loss and delay of a link
+type Estimate struct {
+	Class     string
+	Link      Link
+	LossRatio float64
+	RTT       float64 // Nanoseconds
+}
+
+// Tomographer estimates per link loss and delay from the measurements of all probers
+type Tomographer struct {
+	source Source
+}
+
+// New creates a new tomographer
+func New(source Source) *Tomographer {
+	return &Tomographer{
+		source: source,
+	}
+}
+
+// Estimate estimates all links based on the last finished bucket of all running probers
+func (t *Tomographer) Estimate() []Estimate {
+	obs := make([]Observation, 0)
+	for _, s := range t.source.Status() {
+		if !s.Running || s.Paused {
+			continue
+		}
+
+		measurements, err := t.source.Measurements(s.ID, 1)
+		if err != nil || len(measurements) == 0 || measurements[0].Sent == 0 {
+			continue
+		}
+
+		m := measurements[0]
+		obs = append(obs, Observation{
+			Class:     s.Class,
+			Links:     pathLinks(s.Config.Hops),
+			LossRatio: m.Loss(),
+			RTT:       float64(m.RTTAvg()),
+			HasRTT:    m.Received > 0,
+		})
+	}
+
+	return Solve(obs)
+}
+
+// pathLinks returns the links from the prober along all hops back to the prober
+func pathLinks(hops []prober.Hop) []Link {
+	ret := make([]Link, 0, len(hops)+1)
+	from := correlation.ProberNode
+	for _, h := range hops {
+		ret = append(ret, Link{From: from, To: h.Name})
+		from = h.Name
+	}
+
+	return append(ret, Link{From: from, To: correlation.ProberNode})
+}
+
+// Solve estimates the links of all classes. The RTT of a path is modelled as the sum of its link delays and
+// the success rate of a path as the product of its link success rates. Both are solved as non-negative least squares.
+func Solve(obs []Observation) []Estimate {
+	byClass := make(map[string][]Observation)
+	for _, o := range obs {
+		byClass[o.Class] = append(byClass[o.Class], o)
+	}
+
+	ret := make([]Estimate, 0)
+	for class, obs := range byClass {
+		ret = append(ret, solveClass(class, obs)...)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Class != ret[j].Class {
+			return ret[i].Class < ret[j].Class
+		}
+
+		if ret[i].Link.From != ret[j].Link.From {
+			return ret[i].Link.From < ret[j].Link.From
+		}
+
+		return ret[i].Link.To < ret[j].Link.To
+	})
+
+	return ret
+}
+
+func solveClass(class string, obs []Observation) []Estimate {
+	index := make(map[Link]int)
+	links := make([]Link, 0)
+	for _, o := range obs {
+		for _, l := range o.Links {
+			if _, ok := index[l]; !ok {
+				index[l] = len(links)
+				links = append(links, l)
+			}
+		}
+	}
+
+	lossA := make([][]float64, 0, len(obs))
+	lossY := make([]float64, 0, len(obs))
+	rttA := make([][]float64, 0, len(obs))
+	rttY := make([]float64, 0, len(obs))
+	for _, o := range obs {
+		row := make([]float64, len(links))
+		for _, l := range o.Links {
+			row[index[l]]++
+		}
+
+		lossA = append(lossA, row)
+		lossY = append(lossY, -math.Log(1-math.Min(o.LossRatio, maxLossRatio)))
+
+		if o.HasRTT {
+			rttA = append(rttA, row)
+			rttY = append(rttY, o.RTT)
+		}
+	}
+
+	loss := solve(lossA, lossY, len(links))
+	rtt := solve(rttA, rttY, len(links))
+
+	ret := make([]Estimate, len(links))
+	for i, l := range links {
+		ret[i] = Estimate{
+			Class:     class,
+			Link:      l,
+			LossRatio: 1 - math.Exp(-loss[i]),
+			RTT:       rtt[i],
+		}
+	}
+
+	return ret
+}
+
+// Describe is required by prometheus interface
+func (t *Tomographer) Describe(ch chan<- *prometheus.Desc) {
+}
+
+// Collect collects the link estimates and sends them to prometheus
+func (t *Tomographer) Collect(ch chan<- prometheus.Metric) {
+	lossDesc := prometheus.NewDesc(metricPrefix+"link_loss_ratio", "Estimated loss ratio of a link inferred from all paths", []string{"tos", "from", "to"}, nil)
+	rttDesc := prometheus.NewDesc(metricPrefix+"link_rtt_estimate", "Estimated delay of a link in nanoseconds inferred from the RTTs of all paths", []string{"tos", "from", "to"}, nil)
+
+	for _, e := range t.Estimate() {
+		ch <- prometheus.MustNewConstMetric(lossDesc, prometheus.GaugeValue, e.LossRatio, e.Class, e.Link.From, e.Link.To)
+		ch <- prometheus.MustNewConstMetric(rttDesc, prometheus.GaugeValue, e.RTT, e.Class, e.Link.From, e.Link.To)
+	}
+}
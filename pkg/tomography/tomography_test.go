@@ -0,0 +1,91 @@
+package tomography
+
+import (
+	"testing"
+
+	"github.com/exaring/matroschka-prober/pkg/prober"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSolve(t *testing.T) {
+	xy := Link{From: "x", To: "y"}
+	yz := Link{From: "y", To: "z"}
+	zx := Link{From: "z", To: "x"}
+	pa := Link{From: "prober", To: "a"}
+	ap := Link{From: "a", To: "prober"}
+
+	tests := []struct {
+		name     string
+		obs      []Observation
+		expected []Estimate
+	}{
+		{
+			name: "Test #1: fully determined system",
+			obs: []Observation{
+				{Class: "BE", Links: []Link{xy, yz}, LossRatio: 0.1, RTT: 3, HasRTT: true},
+				{Class: "BE", Links: []Link{yz, zx}, LossRatio: 0.5, RTT: 5, HasRTT: true},
+				{Class: "BE", Links: []Link{xy, zx}, LossRatio: 0.55, RTT: 4, HasRTT: true},
+			},
+			expected: []Estimate{
+				{Class: "BE", Link: xy, LossRatio: 0.1, RTT: 1},
+				{Class: "BE", Link: yz, LossRatio: 0, RTT: 2},
+				{Class: "BE", Link: zx, LossRatio: 0.5, RTT: 3},
+			},
+		},
+		{
+			name: "Test #2: negative estimates are clamped",
+			obs: []Observation{
+				{Class: "BE", Links: []Link{xy, yz}, RTT: 1, HasRTT: true},
+				{Class: "BE", Links: []Link{yz}, RTT: 3, HasRTT: true},
+			},
+			expected: []Estimate{
+				{Class: "BE", Link: xy, RTT: 0},
+				{Class: "BE", Link: yz, RTT: 2},
+			},
+		},
+		{
+			name: "Test #3: indistinguishable links share the estimate and classes are solved separately",
+			obs: []Observation{
+				{Class: "BE", Links: []Link{pa, ap}, LossRatio: 0.19, RTT: 2, HasRTT: true},
+				{Class: "EF", Links: []Link{pa, ap}, LossRatio: 1},
+			},
+			expected: []Estimate{
+				{Class: "BE", Link: ap, LossRatio: 0.1, RTT: 1},
+				{Class: "BE", Link: pa, LossRatio: 0.1, RTT: 1},
+				{Class: "EF", Link: ap, LossRatio: 0.9683772233983162, RTT: 0},
+				{Class: "EF", Link: pa, LossRatio: 0.9683772233983162, RTT: 0},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		res := Solve(test.obs)
+		if !assert.Len(t, res, len(test.expected), test.name) {
+			continue
+		}
+
+		for i := range res {
+			assert.Equal(t, test.expected[i].Class, res[i].Class, test.name)
+			assert.Equal(t, test.expected[i].Link, res[i].Link, test.name)
+			assert.InDelta(t, test.expected[i].LossRatio, res[i].LossRatio, 1e-4, test.name)
+			assert.InDelta(t, test.expected[i].RTT, res[i].RTT, 1e-4, test.name)
+		}
+	}
+}
+
+func TestPathLinks(t *testing.T) {
+	hops := []prober.Hop{
+		{Name: "a"},
+		{Name: "b"},
+		{Name: "a"},
+	}
+
+	expected := []Link{
+		{From: "prober", To: "a"},
+		{From: "a", To: "b"},
+		{From: "b", To: "a"},
+		{From: "a", To: "prober"},
+	}
+
+	assert.Equal(t, expected, pathLinks(hops))
+}